@@ -23,10 +23,14 @@ type Master struct {
 	intv  time.Duration // duration of a cycle
 	pos   uint64        // clocks so far
 	state ClockState
+	busy  bool // true while the clock thread is running a listener
+	live  bool // true once the clock thread has been started
 	now   time.Time
 	next  *Listener
 	mu    sync.Mutex
 	cd    *sync.Cond
+
+	listeners []*Listener // all listeners, in order of registration
 }
 
 // New returns a new clock running at the given frequency, using the specified
@@ -75,7 +79,8 @@ func New(freq uint64) *Master {
 	}
 	res.cd = sync.NewCond(&res.mu)
 
-	go res.thread()
+	// the thread running the clock in real time is only started on Start(),
+	// so the clock can also be run synchronously (see RunCycles)
 
 	// Sample usage:
 	//res.Listen(freq/10, func(uint64) uint64 { log.Printf("Clock: test @1/10th of a sec"); return 1 })
@@ -97,6 +102,7 @@ func (m *Master) Listen(divider, delta uint64, cb ClockInput) *Listener {
 	}
 
 	l.nextRun = ((atomic.LoadUint64(&m.pos)/l.divider)+1)*l.divider + l.delta
+	m.listeners = append(m.listeners, l)
 	m.insert(l)
 	return l
 }
@@ -104,8 +110,6 @@ func (m *Master) Listen(divider, delta uint64, cb ClockInput) *Listener {
 // The NES cpu runs with a divider of 12 or 16, the NES PPU runs with a divider of 4, and the NES APU runs with a divider of 12*240
 
 func (m *Master) thread() {
-	var pos uint64 // master value for pos (m.pos is a slave)
-
 	for {
 		cur := m.takeNext()
 		if cur == nil {
@@ -113,6 +117,8 @@ func (m *Master) thread() {
 			m.now = time.Now()
 			continue
 		}
+		// only this thread updates m.pos while running, but it can be changed while stopped (see LoadState)
+		pos := atomic.LoadUint64(&m.pos)
 		if cur.nextRun > pos {
 			// this doesn't need to run yet?
 			now := time.Now()
@@ -173,6 +179,12 @@ func (m *Master) takeNext() *Listener {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	if m.busy {
+		// previous listener is done running
+		m.busy = false
+		m.cd.Broadcast()
+	}
+
 stateLoop:
 	for {
 		switch m.state {
@@ -189,6 +201,7 @@ stateLoop:
 	next := m.next
 	if next != nil {
 		m.next = next.next
+		m.busy = true
 	}
 	return next
 }
@@ -200,6 +213,11 @@ func (m *Master) Start() {
 	m.state = Running
 	m.now = time.Now() // reset wallclock time
 	m.cd.Broadcast()   // wake thread if needed
+
+	if !m.live {
+		m.live = true
+		go m.thread()
+	}
 }
 
 func (m *Master) Stop() {
@@ -209,6 +227,21 @@ func (m *Master) Stop() {
 	m.state = Stopped
 }
 
+// Pause stops the clock and waits for any listener currently running to
+// return, so the state of the machine can be safely accessed. It returns true
+// if the clock was running. Pause must not be called from a listener.
+func (m *Master) Pause() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	running := m.state == Running
+	m.state = Stopped
+	for m.busy {
+		m.cd.Wait()
+	}
+	return running
+}
+
 func (m *Master) insert(l *Listener) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -0,0 +1,48 @@
+package clock
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"sync/atomic"
+)
+
+// SaveState writes the position of the clock and of all its listeners to w.
+// The clock must be stopped (see Pause).
+func (m *Master) SaveState(w io.Writer) error {
+	st := make([]uint64, 0, len(m.listeners)+2)
+	st = append(st, atomic.LoadUint64(&m.pos), uint64(len(m.listeners)))
+	for _, l := range m.listeners {
+		st = append(st, l.nextRun)
+	}
+	return binary.Write(w, binary.LittleEndian, st)
+}
+
+// LoadState restores a state written by SaveState. Listeners are matched in
+// order of registration, so the same listeners must have been registered in
+// the same order. The clock must be stopped (see Pause).
+func (m *Master) LoadState(r io.Reader) error {
+	var hdr [2]uint64
+	if err := binary.Read(r, binary.LittleEndian, &hdr); err != nil {
+		return err
+	}
+	if hdr[1] != uint64(len(m.listeners)) {
+		return errors.New("clock: state has a different number of listeners")
+	}
+	nextRun := make([]uint64, len(m.listeners))
+	if err := binary.Read(r, binary.LittleEndian, nextRun); err != nil {
+		return err
+	}
+
+	atomic.StoreUint64(&m.pos, hdr[0])
+
+	m.mu.Lock()
+	m.next = nil
+	m.mu.Unlock()
+
+	for n, l := range m.listeners {
+		l.nextRun = nextRun[n]
+		m.insert(l)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package clock
+
+import (
+	"errors"
+	"math"
+	"sync/atomic"
+)
+
+// ErrStopped is returned when running the clock synchronously if a listener
+// ran zero cycles, which means it stopped the machine (for example the CPU
+// when its Hook returns false). The listener is called again at the same
+// time on the next run.
+var ErrStopped = errors.New("clock: stopped by listener")
+
+// RunCycles runs the clock synchronously for n master clock ticks, calling
+// listeners in order as fast as possible without tracking real time. This is
+// meant for headless use (tests, batch jobs) and must not be called while the
+// clock is running (see Start).
+func (m *Master) RunCycles(n uint64) error {
+	end := atomic.LoadUint64(&m.pos) + n
+	if err := m.run(end, nil); err != nil {
+		return err
+	}
+	atomic.StoreUint64(&m.pos, end)
+	return nil
+}
+
+// RunUntil runs the clock synchronously until pred returns true. pred is
+// checked after each listener call. Like RunCycles, it must not be called
+// while the clock is running.
+func (m *Master) RunUntil(pred func() bool) error {
+	return m.run(math.MaxUint64, pred)
+}
+
+// run calls listeners due before end, stopping early if pred returns true, or
+// with ErrStopped if a listener stopped the machine
+func (m *Master) run(end uint64, pred func() bool) error {
+	m.mu.Lock()
+	if m.state == Running {
+		m.mu.Unlock()
+		panic("clock: cannot run synchronously while the clock is running")
+	}
+	m.mu.Unlock()
+
+	for {
+		m.mu.Lock()
+		cur := m.next
+		if cur == nil || cur.nextRun > end {
+			m.mu.Unlock()
+			return nil
+		}
+		m.next = cur.next
+		m.mu.Unlock()
+
+		if cur.nextRun > atomic.LoadUint64(&m.pos) {
+			atomic.StoreUint64(&m.pos, cur.nextRun)
+		}
+
+		cnt := cur.run(1)
+		cur.nextRun += cur.divider * cnt
+		m.insert(cur)
+		if cnt == 0 {
+			return ErrStopped
+		}
+
+		if pred != nil && pred() {
+			return nil
+		}
+	}
+}
+
+// CatchUp is called from the callback of listener l to run the other
+// listeners that are due before l's current run plus n of its cycles. This
+// lets a listener that runs several of its cycles at once (such as the CPU
+// running a whole instruction) bring the rest of the machine up to date before
+// accessing shared state. Listeners run this way are not run again later.
+func (m *Master) CatchUp(l *Listener, n uint64) {
+	until := l.nextRun + n*l.divider
+
+	for {
+		m.mu.Lock()
+		cur := m.next
+		if cur == nil || cur.nextRun >= until {
+			m.mu.Unlock()
+			return
+		}
+		m.next = cur.next
+		m.mu.Unlock()
+
+		cnt := cur.run(1)
+		cur.nextRun += cur.divider * cnt
+		m.insert(cur)
+	}
+}
@@ -0,0 +1,45 @@
+package clock
+
+import "testing"
+
+func TestRunCycles(t *testing.T) {
+	m := New(1000)
+	var a, b int
+	m.Listen(10, 0, func(uint64) uint64 { a++; return 1 })
+	m.Listen(25, 1, func(uint64) uint64 { b++; return 2 })
+
+	if err := m.RunCycles(100); err != nil {
+		t.Fatal(err)
+	}
+	if a != 10 || b != 2 {
+		t.Errorf("listeners ran %d and %d times, want 10 and 2", a, b)
+	}
+}
+
+func TestRunStopped(t *testing.T) {
+	m := New(1000)
+	var n int
+	m.Listen(10, 0, func(uint64) uint64 {
+		n++
+		if n == 3 {
+			// stop the machine, once
+			return 0
+		}
+		return 1
+	})
+
+	if err := m.RunUntil(func() bool { return false }); err != ErrStopped {
+		t.Fatalf("RunUntil returned %v, want ErrStopped", err)
+	}
+	if n != 3 {
+		t.Errorf("listener ran %d times, want 3", n)
+	}
+
+	// the listener is called again at the same time (30), then at 40
+	if err := m.RunCycles(10); err != nil {
+		t.Fatal(err)
+	}
+	if n != 5 {
+		t.Errorf("listener ran %d times after running again, want 5", n)
+	}
+}
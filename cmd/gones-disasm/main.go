@@ -0,0 +1,169 @@
+// gones-disasm disassembles the PRG ROM banks of a .nes file, split in banks
+// of the size switched by its mapper
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/MagicalTux/gones/cpu6502"
+	"github.com/MagicalTux/gones/nescartridge"
+)
+
+var (
+	bankFlag = flag.Int("bank", -1, "PRG bank to disassemble, banks having the size switched by the mapper (default: all banks)")
+	baseFlag = flag.Int("base", -1, "address at which banks are mapped (default: the end of the address space for the last bank, $8000 for others)")
+)
+
+// hardware registers names
+// see: https://www.nesdev.org/wiki/PPU_registers and https://www.nesdev.org/wiki/APU_registers
+var registers = map[uint16]string{
+	0x2000: "PPUCTRL",
+	0x2001: "PPUMASK",
+	0x2002: "PPUSTATUS",
+	0x2003: "OAMADDR",
+	0x2004: "OAMDATA",
+	0x2005: "PPUSCROLL",
+	0x2006: "PPUADDR",
+	0x2007: "PPUDATA",
+	0x4000: "SQ1_VOL",
+	0x4001: "SQ1_SWEEP",
+	0x4002: "SQ1_LO",
+	0x4003: "SQ1_HI",
+	0x4004: "SQ2_VOL",
+	0x4005: "SQ2_SWEEP",
+	0x4006: "SQ2_LO",
+	0x4007: "SQ2_HI",
+	0x4008: "TRI_LINEAR",
+	0x400a: "TRI_LO",
+	0x400b: "TRI_HI",
+	0x400c: "NOISE_VOL",
+	0x400e: "NOISE_LO",
+	0x400f: "NOISE_HI",
+	0x4010: "DMC_FREQ",
+	0x4011: "DMC_RAW",
+	0x4012: "DMC_START",
+	0x4013: "DMC_LEN",
+	0x4014: "OAMDMA",
+	0x4015: "SND_CHN",
+	0x4016: "JOY1",
+	0x4017: "JOY2",
+}
+
+func main() {
+	flag.Parse()
+
+	arg := flag.Args()
+	if len(arg) != 1 {
+		log.Printf("Usage: %s [-bank N] [-base ADDR] file.nes", os.Args[0])
+		os.Exit(1)
+	}
+
+	data, err := nescartridge.Load(arg[0])
+	if err != nil {
+		log.Printf("Failed to load %s: %s", arg[0], err)
+		os.Exit(1)
+	}
+	defer data.Close()
+
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+
+	if err := disasm(out, data.PRG(), data.PRGBankSize(), *bankFlag, *baseFlag); err != nil {
+		log.Printf("%s: %s", arg[0], err)
+		out.Flush()
+		os.Exit(1)
+	}
+}
+
+// disasm disassembles prg in banks of bankSize bytes, only bank if it isn't
+// -1, at base if it isn't -1
+func disasm(out *bufio.Writer, prg []byte, bankSize, bank, base int) error {
+	cnt := len(prg) / bankSize
+	if cnt == 0 {
+		return errors.New("no PRG ROM")
+	}
+	if bank >= cnt {
+		return fmt.Errorf("only %d banks", cnt)
+	}
+
+	// vectors are at the end of the last bank, which is mapped at the end of the address space by most mappers
+	last := prg[len(prg)-bankSize:]
+	vectors := map[uint16]string{}
+	for _, v := range []struct {
+		name string
+		offt int
+	}{{"NMI", bankSize - 6}, {"RESET", bankSize - 4}, {"IRQ", bankSize - 2}} {
+		addr := uint16(last[v.offt]) | uint16(last[v.offt+1])<<8
+		if name, ok := vectors[addr]; ok {
+			vectors[addr] = name + "_" + v.name
+		} else {
+			vectors[addr] = v.name
+		}
+	}
+
+	for n := 0; n < cnt; n++ {
+		if bank != -1 && n != bank {
+			continue
+		}
+		addr := uint16(0x8000)
+		if n == cnt-1 {
+			addr = uint16(0x10000 - bankSize)
+		}
+		if base != -1 {
+			addr = uint16(base)
+		}
+		disasmBank(out, n, prg[n*bankSize:(n+1)*bankSize], addr, vectors, n == cnt-1)
+	}
+	return nil
+}
+
+func disasmBank(out *bufio.Writer, n int, bank []byte, base uint16, vectors map[uint16]string, isLast bool) {
+	code := bank
+	if isLast {
+		// do not disassemble the vectors
+		code = bank[:len(bank)-6]
+	}
+	ins := cpu6502.Disassemble(code, base)
+	end := base + uint16(len(bank)-1)
+
+	// label addresses used by jumps & branches within this bank
+	labels := map[uint16]string{}
+	for _, i := range ins {
+		if t, ok := i.Target(); ok && t >= base && t <= end {
+			labels[t] = fmt.Sprintf("L_%04X", t)
+		}
+	}
+	if isLast {
+		for addr, name := range vectors {
+			labels[addr] = name
+		}
+	}
+
+	label := func(addr uint16) string {
+		if l, ok := labels[addr]; ok {
+			return l
+		}
+		return registers[addr]
+	}
+
+	fmt.Fprintf(out, "; bank %d ($%04X-$%04X)\n", n, base, end)
+	for _, i := range ins {
+		if l, ok := labels[i.Addr]; ok {
+			fmt.Fprintf(out, "%s:\n", l)
+		}
+		fmt.Fprintf(out, "$%04X  %-8s  %s\n", i.Addr, i.Hex(), i.Format(label))
+	}
+	if isLast {
+		vec := bank[len(bank)-6:]
+		for n, name := range []string{"NMI", "RESET", "IRQ"} {
+			addr := uint16(vec[n*2]) | uint16(vec[n*2+1])<<8
+			fmt.Fprintf(out, "$%04X  %02X %02X     .word $%04X ; %s vector\n", end-5+uint16(n*2), vec[n*2], vec[n*2+1], addr, name)
+		}
+	}
+	fmt.Fprintf(out, "\n")
+}
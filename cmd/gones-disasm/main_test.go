@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+)
+
+// nestestStart is the code at $C5F5 in nestest.nes, the start of its
+// automated mode, followed by its first test at $C72D
+var nestestStart = []byte{
+	0xa2, 0x00, // LDX #$00
+	0x86, 0x00, // STX $00
+	0x86, 0x10, // STX $10
+	0x86, 0x11, // STX $11
+	0x20, 0x2d, 0xc7, // JSR $C72D
+}
+
+// testPRG returns size bytes of PRG ROM filled with NOPs, with code at
+// offset and the vectors pointing to nmi, reset and irq in the last 6 bytes
+func testPRG(size, offset int, code []byte, nmi, reset, irq uint16) []byte {
+	prg := make([]byte, size)
+	for i := range prg {
+		prg[i] = 0xea
+	}
+	copy(prg[offset:], code)
+	copy(prg[size-6:], []byte{byte(nmi), byte(nmi >> 8), byte(reset), byte(reset >> 8), byte(irq), byte(irq >> 8)})
+	return prg
+}
+
+func TestDisasm(t *testing.T) {
+	tests := []struct {
+		name     string
+		prg      []byte
+		bankSize int
+		bank     int
+		want     []string // lines expected in the listing, in order
+	}{
+		{
+			name:     "16 KB banks",
+			prg:      append(make([]byte, 0x4000), testPRG(0x4000, 0x5f5, nestestStart, 0xc5af, 0xc004, 0xc5f4)...),
+			bankSize: 0x4000,
+			bank:     1,
+			want: []string{
+				"; bank 1 ($C000-$FFFF)",
+				"IRQ:",
+				"$C5F4  EA        NOP",
+				"$C5F5  A2 00     LDX #$00",
+				"$C5F7  86 00     STX $00",
+				"$C5F9  86 10     STX $10",
+				"$C5FB  86 11     STX $11",
+				"$C5FD  20 2D C7  JSR L_C72D",
+				"L_C72D:",
+				"$FFFA  AF C5     .word $C5AF ; NMI vector",
+				"$FFFC  04 C0     .word $C004 ; RESET vector",
+				"$FFFE  F4 C5     .word $C5F4 ; IRQ vector",
+			},
+		},
+		{
+			name:     "8 KB banks",
+			prg:      append(make([]byte, 0x6000), testPRG(0x2000, 0x5f5, append(nestestStart[:8:8], 0x20, 0x2d, 0xe7), 0xe000, 0xe5f5, 0xe000)...),
+			bankSize: 0x2000,
+			bank:     3,
+			want: []string{
+				"; bank 3 ($E000-$FFFF)",
+				"NMI_IRQ:",
+				"$E000  EA        NOP",
+				"RESET:",
+				"$E5F5  A2 00     LDX #$00",
+				"$E5FD  20 2D E7  JSR L_E72D",
+				"L_E72D:",
+				"$FFFC  F5 E5     .word $E5F5 ; RESET vector",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf strings.Builder
+			out := bufio.NewWriter(&buf)
+			if err := disasm(out, tt.prg, tt.bankSize, tt.bank, -1); err != nil {
+				t.Fatal(err)
+			}
+			out.Flush()
+
+			lines := strings.Split(buf.String(), "\n")
+			pos := 0
+			for _, want := range tt.want {
+				for pos < len(lines) && strings.TrimRight(lines[pos], " ") != want {
+					pos++
+				}
+				if pos == len(lines) {
+					t.Fatalf("listing is missing %q (or it is out of order):\n%s", want, buf.String())
+				}
+			}
+		})
+	}
+}
+
+func TestDisasmErrors(t *testing.T) {
+	var buf strings.Builder
+	out := bufio.NewWriter(&buf)
+	if err := disasm(out, make([]byte, 0x8000), 0x4000, 2, -1); err == nil || err.Error() != "only 2 banks" {
+		t.Errorf("got error %v, want only 2 banks", err)
+	}
+}
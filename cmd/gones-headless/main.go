@@ -0,0 +1,77 @@
+// gones-headless runs a ROM without display, audio or real time tracking,
+// and writes the last rendered frame as a PNG image.
+package main
+
+import (
+	"flag"
+	"image"
+	"image/png"
+	"log"
+	"os"
+
+	"github.com/MagicalTux/gones/nescartridge"
+	"github.com/MagicalTux/gones/pkgnes"
+)
+
+// maxFrames caps -frames to one hour of emulated time, so a mistyped count
+// doesn't run forever
+const maxFrames = 60 * 60 * 60
+
+var (
+	frames = flag.Int("frames", 60, "number of frames to run")
+	output = flag.String("o", "frame.png", "file to write the final frame to")
+)
+
+func main() {
+	flag.Parse()
+
+	arg := flag.Args()
+	if len(arg) != 1 {
+		log.Printf("Usage: %s [-frames N] [-o frame.png] file.nes", os.Args[0])
+		os.Exit(1)
+	}
+
+	if *frames <= 0 || *frames > maxFrames {
+		log.Printf("-frames must be between 1 and %d", maxFrames)
+		os.Exit(1)
+	}
+
+	data, err := nescartridge.Load(arg[0])
+	if err != nil {
+		log.Printf("Failed to load %s: %s", arg[0], err)
+		os.Exit(1)
+	}
+	defer data.Close()
+
+	nes := pkgnes.New(data.Model())
+	nes.APU.NoAudio = true
+
+	if err := data.Setup(nes); err != nil {
+		log.Printf("Failed to map %s: %s", arg[0], err)
+		os.Exit(1)
+	}
+
+	nes.Reset()
+	for i := 0; i < *frames; i++ {
+		if err := nes.RunFrame(); err != nil {
+			log.Printf("Emulation stopped at frame %d: %s", i, err)
+			os.Exit(1)
+		}
+	}
+
+	f, err := os.Create(*output)
+	if err != nil {
+		log.Printf("Failed to create %s: %s", *output, err)
+		os.Exit(1)
+	}
+	nes.PPU.Front(func(img *image.RGBA) {
+		err = png.Encode(f, img)
+	})
+	if err2 := f.Close(); err == nil {
+		err = err2
+	}
+	if err != nil {
+		log.Printf("Failed to write %s: %s", *output, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,122 @@
+// gones-tracediff compares two CPU traces and reports the first divergence
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/MagicalTux/gones/cpu6502"
+)
+
+var (
+	context = flag.Int("context", 5, "number of matching lines to show before the divergence")
+	noCyc   = flag.Bool("nocyc", false, "do not compare CPU cycles")
+	noPPU   = flag.Bool("noppu", false, "do not compare the PPU position")
+)
+
+func main() {
+	flag.Parse()
+
+	arg := flag.Args()
+	if len(arg) != 2 {
+		log.Printf("Usage: %s [-context N] [-nocyc] [-noppu] expected.log actual.log", os.Args[0])
+		os.Exit(2)
+	}
+
+	a, err := open(arg[0])
+	if err != nil {
+		log.Printf("Failed to open %s: %s", arg[0], err)
+		os.Exit(2)
+	}
+	b, err := open(arg[1])
+	if err != nil {
+		log.Printf("Failed to open %s: %s", arg[1], err)
+		os.Exit(2)
+	}
+
+	// last lines that matched, for context
+	var prev []string
+
+	for n := 1; ; n++ {
+		okA, okB := a.Scan(), b.Scan()
+		if !okA || !okB {
+			for _, s := range []*bufio.Scanner{a, b} {
+				if err := s.Err(); err != nil {
+					log.Printf("Failed to read: %s", err)
+					os.Exit(2)
+				}
+			}
+			switch {
+			case okA:
+				report(n, prev, a.Text(), "", fmt.Sprintf("%s ends at line %d", arg[1], n-1))
+			case okB:
+				report(n, prev, "", b.Text(), fmt.Sprintf("%s ends at line %d", arg[0], n-1))
+			default:
+				fmt.Printf("traces match (%d lines)\n", n-1)
+				return
+			}
+			os.Exit(1)
+		}
+
+		lineA, lineB := a.Text(), b.Text()
+		if diff := compare(lineA, lineB); diff != "" {
+			report(n, prev, lineA, lineB, diff)
+			os.Exit(1)
+		}
+
+		if *context > 0 {
+			if len(prev) >= *context {
+				prev = prev[1:]
+			}
+			prev = append(prev, lineA)
+		}
+	}
+}
+
+func open(fn string) (*bufio.Scanner, error) {
+	f, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	s := bufio.NewScanner(f)
+	s.Buffer(nil, 1024*1024)
+	return s, nil
+}
+
+// compare returns a description of the differences between two lines, or an
+// empty string if they match. Lines that can't be parsed are compared as text.
+func compare(a, b string) string {
+	la, errA := cpu6502.ParseTraceLine(a)
+	lb, errB := cpu6502.ParseTraceLine(b)
+	if errA != nil || errB != nil {
+		if strings.TrimSpace(a) == strings.TrimSpace(b) {
+			return ""
+		}
+		return "lines differ"
+	}
+
+	if *noCyc {
+		la.Cyc, lb.Cyc = 0, 0
+	}
+	if *noPPU {
+		la.PPU, lb.PPU = false, false
+	}
+	return strings.Join(la.Diff(lb), " ")
+}
+
+func report(n int, prev []string, a, b, diff string) {
+	fmt.Printf("first divergence at line %d: %s\n", n, diff)
+	for i, l := range prev {
+		fmt.Printf("  %6d  %s\n", n-len(prev)+i, l)
+	}
+	if a != "" {
+		fmt.Printf("- %6d  %s\n", n, a)
+	}
+	if b != "" {
+		fmt.Printf("+ %6d  %s\n", n, b)
+	}
+}
@@ -18,6 +18,11 @@ rel	relative	OPC $BB	branch target is PC + signed offset BB ***
 zpg	zeropage	OPC $LL	operand is zeropage address (hi-byte is zero, address = $00LL)
 zpg,X	zeropage, X-indexed	OPC $LL,X	operand is zeropage address; effective address is address incremented by X without carry **
 zpg,Y	zeropage, Y-indexed	OPC $LL,Y	operand is zeropage address; effective address is address incremented by Y without carry **
+
+65C02 only:
+(zpg)	zeropage indirect	OPC ($LL)	operand is zeropage address; effective address is word in (LL, LL + 1)
+(abs,X)	absolute X-indexed indirect	OPC ($LLHH,X)	operand is address; effective address is word at address incremented by X (JMP only)
+zpg,rel	zeropage, relative	OPC $LL,$BB	tests a bit of the byte at zeropage address LL, branch target is PC + signed offset BB (BBR/BBS only)
 */
 
 type AddressMode byte
@@ -37,6 +42,9 @@ const (
 	amZpg
 	amZpgX
 	amZpgY
+	amZpgInd  // 65C02
+	amAbsIndX // 65C02
+	amZpgRel  // 65C02
 )
 
 func (am AddressMode) Addr(cpu *CPU) uint16 {
@@ -51,8 +59,7 @@ func (am AddressMode) Addr(cpu *CPU) uint16 {
 		addr2 := addr + uint16(cpu.X)
 		if addr&0xff00 != addr2&0xff00 {
 			// different page, lose one cycle due to dummy read
-			cpu.Memory.MemRead((addr & 0xff00) | (addr2 & 0xff)) // dummy read
-			cpu.cyc += 1
+			cpu.read((addr & 0xff00) | (addr2 & 0xff)) // dummy read
 		}
 		return addr2
 	case amAbsY:
@@ -61,26 +68,36 @@ func (am AddressMode) Addr(cpu *CPU) uint16 {
 		addr2 := addr + uint16(cpu.Y)
 		if addr&0xff00 != addr2&0xff00 {
 			// different page, lose one cycle due to dummy read
-			cpu.Memory.MemRead((addr & 0xff00) | (addr2 & 0xff)) // dummy read
-			cpu.cyc += 1
+			cpu.read((addr & 0xff00) | (addr2 & 0xff)) // dummy read
 		}
 		return addr2
 	case amImmed:
 		panic("amImmed.Addr()")
 	case amInd:
 		addr := cpu.ReadPC16()
+		if cpu.variant == Variant65C02 {
+			// the page wrap bug is fixed at the cost of one cycle
+			cpu.read(cpu.PC - 1)
+			return cpu.read16(addr)
+		}
 		return cpu.Read16W(addr)
+	case amZpgInd:
+		return cpu.Read16W(uint16(cpu.ReadPC()))
+	case amAbsIndX:
+		addr := cpu.ReadPC16()
+		cpu.read(cpu.PC - 1) // dummy read while X is added
+		return cpu.read16(addr + uint16(cpu.X))
 	case amIndX:
-		addr := uint16(cpu.ReadPC() + cpu.X)
-		return cpu.Read16W(addr)
+		ptr := cpu.ReadPC()
+		cpu.read(uint16(ptr)) // dummy read while X is added
+		return cpu.Read16W(uint16(ptr + cpu.X))
 	case amIndY:
 		addr := uint16(cpu.ReadPC())
 		addr = cpu.Read16W(addr)
 		addr2 := addr + uint16(cpu.Y)
 		if addr&0xff00 != addr2&0xff00 {
 			// different page, lose one cycle due to dummy read
-			cpu.Memory.MemRead((addr & 0xff00) | (addr2 & 0xff)) // dummy read
-			cpu.cyc += 1
+			cpu.read((addr & 0xff00) | (addr2 & 0xff)) // dummy read
 		}
 		return addr2
 	case amRel:
@@ -92,30 +109,37 @@ func (am AddressMode) Addr(cpu *CPU) uint16 {
 	case amZpg:
 		return uint16(cpu.ReadPC())
 	case amZpgX:
-		return uint16(cpu.ReadPC() + cpu.X)
+		addr := cpu.ReadPC()
+		cpu.read(uint16(addr)) // dummy read while X is added
+		return uint16(addr + cpu.X)
 	case amZpgY:
-		return uint16(cpu.ReadPC() + cpu.Y)
+		addr := cpu.ReadPC()
+		cpu.read(uint16(addr)) // dummy read while Y is added
+		return uint16(addr + cpu.Y)
 	default:
 		panic("unhandled address mode")
 	}
 }
 
+// AddrFast returns the address for writes and read-modify-write operations.
+// Unlike Addr, indexed modes always spend a cycle reading from the address
+// before the page is fixed, as the CPU can't know yet if it needs fixing.
 func (am AddressMode) AddrFast(cpu *CPU) uint16 {
 	switch am {
 	case amAbsX:
 		addr := cpu.ReadPC16()
 		addr2 := addr + uint16(cpu.X)
-		cpu.Memory.MemRead((addr & 0xff00) | (addr2 & 0xff)) // pre-write read
+		cpu.read((addr & 0xff00) | (addr2 & 0xff)) // pre-write read
 		return addr2
 	case amAbsY:
 		addr := cpu.ReadPC16()
 		addr2 := addr + uint16(cpu.Y)
-		cpu.Memory.MemRead((addr & 0xff00) | (addr2 & 0xff)) // pre-write read
+		cpu.read((addr & 0xff00) | (addr2 & 0xff)) // pre-write read
 		return addr2
 	case amIndY:
 		addr := cpu.Read16W(uint16(cpu.ReadPC()))
 		addr2 := addr + uint16(cpu.Y)
-		cpu.Memory.MemRead((addr & 0xff00) | (addr2 & 0xff)) // pre-write read
+		cpu.read((addr & 0xff00) | (addr2 & 0xff)) // pre-write read
 		return addr2
 	default:
 		return am.Addr(cpu)
@@ -134,7 +158,7 @@ func (am AddressMode) Read(cpu *CPU) byte {
 		// can only Addr() this
 		panic("amRel.Read()")
 	default:
-		return cpu.Memory.MemRead(am.Addr(cpu))
+		return cpu.read(am.Addr(cpu))
 	}
 }
 
@@ -150,17 +174,7 @@ func (am AddressMode) Write(cpu *CPU, v byte) {
 		// can only Addr() this
 		panic("amRel.Write()")
 	default:
-		cpu.Memory.MemWrite(am.Addr(cpu), v)
-	}
-}
-
-// WriteFast writes without losing cycles
-func (am AddressMode) WriteFast(cpu *CPU, v byte) {
-	switch am {
-	case amAbsX, amAbsY, amIndY:
-		cpu.Memory.MemWrite(am.AddrFast(cpu), v)
-	default:
-		am.Write(cpu, v)
+		cpu.write(am.AddrFast(cpu), v)
 	}
 }
 
@@ -200,8 +214,19 @@ func (am AddressMode) Debug(cpu *CPU) string {
 		return fmt.Sprintf("zpg,X = $%02x,$%02x", cpu.PeekPC(), cpu.X)
 	case amZpgY:
 		return fmt.Sprintf("zpg,Y = $%02x,$%02x", cpu.PeekPC(), cpu.Y)
+	case amZpgInd:
+		return fmt.Sprintf("(zpg) = ($%02x)", cpu.PeekPC())
+	case amAbsIndX:
+		return fmt.Sprintf("(abs,X) = ($%04x,$%02x)", cpu.PeekPC16(), cpu.X)
+	case amZpgRel:
+		offt := uint16(cpu.Memory.MemRead(cpu.PC + 1))
+		if offt&0x80 == 0x80 {
+			offt |= 0xff00
+		}
+		// add 2 because we used PeekPC instead of ReadPC
+		return fmt.Sprintf("zpg,rel = $%02x,$%04x", cpu.PeekPC(), cpu.PC+offt+2)
 	default:
-		return fmt.Sprintf("unknown $%02x", am)
+		return fmt.Sprintf("unknown $%02x", byte(am))
 	}
 }
 
@@ -209,9 +234,9 @@ func (am AddressMode) Length() int {
 	switch am {
 	case amAcc, amImpl:
 		return 0
-	case amImmed, amIndX, amIndY, amRel, amZpg, amZpgX, amZpgY:
+	case amImmed, amIndX, amIndY, amRel, amZpg, amZpgX, amZpgY, amZpgInd:
 		return 1
-	case amAbs, amAbsX, amAbsY, amInd:
+	case amAbs, amAbsX, amAbsY, amInd, amAbsIndX, amZpgRel:
 		return 2
 	default:
 		return 0
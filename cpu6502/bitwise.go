@@ -47,12 +47,12 @@ func ror(cpu *CPU, am AddressMode) {
 		cpu.flagsNZ(cpu.A)
 	} else {
 		// act on mem
-		addr := am.AddrFast(cpu)
-		v := cpu.Memory.MemRead(addr)
+		addr := cpu.addrShift(am)
+		v := cpu.readModify(addr)
 
 		cpu.setFlag(FlagCarry, v&1 == 1)
 		v = (v >> 1) | (c << 7)
-		cpu.Memory.MemWrite(addr, v)
+		cpu.write(addr, v)
 		cpu.flagsNZ(v)
 	}
 }
@@ -63,12 +63,12 @@ func lsr(cpu *CPU, am AddressMode) {
 		cpu.A >>= 1
 		cpu.flagsNZ(cpu.A)
 	} else {
-		addr := am.AddrFast(cpu)
-		v := cpu.Memory.MemRead(addr)
+		addr := cpu.addrShift(am)
+		v := cpu.readModify(addr)
 
 		cpu.setFlag(FlagCarry, v&1 == 1)
 		v >>= 1
-		cpu.Memory.MemWrite(addr, v)
+		cpu.write(addr, v)
 		cpu.flagsNZ(v)
 	}
 }
@@ -79,12 +79,12 @@ func asl(cpu *CPU, am AddressMode) {
 		cpu.A <<= 1
 		cpu.flagsNZ(cpu.A)
 	} else {
-		addr := am.AddrFast(cpu)
-		v := cpu.Memory.MemRead(addr)
+		addr := cpu.addrShift(am)
+		v := cpu.readModify(addr)
 
 		cpu.setFlag(FlagCarry, v&0x80 == 0x80)
 		v <<= 1
-		cpu.Memory.MemWrite(addr, v)
+		cpu.write(addr, v)
 		cpu.flagsNZ(v)
 	}
 }
@@ -102,17 +102,22 @@ func rol(cpu *CPU, am AddressMode) {
 		cpu.flagsNZ(cpu.A)
 	} else {
 		// act on mem
-		addr := am.AddrFast(cpu)
-		v := cpu.Memory.MemRead(addr)
-
-		// re-write the original value as per 6502
-		// See: https://www.nesdev.org/6502_cpu.txt
-		// → "Read-Modify-Write instructions"
-		cpu.Memory.MemWrite(addr, v)
+		addr := cpu.addrShift(am)
+		v := cpu.readModify(addr)
 
 		cpu.setFlag(FlagCarry, v&0x80 == 0x80)
 		v = (v << 1) | c
-		cpu.Memory.MemWrite(addr, v)
+		cpu.write(addr, v)
 		cpu.flagsNZ(v)
 	}
 }
+
+// addrShift returns the address for shift and rotate instructions. Unlike
+// the NMOS 6502, the 65C02 only spends a cycle fixing the page of indexed
+// addresses if needed.
+func (cpu *CPU) addrShift(am AddressMode) uint16 {
+	if cpu.variant == Variant65C02 {
+		return am.Addr(cpu)
+	}
+	return am.AddrFast(cpu)
+}
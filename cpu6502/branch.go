@@ -1,12 +1,18 @@
 package cpu6502
 
-// branchTo branches execution to the given address while accounting for cycles
+// branchTo branches execution to the given address. This takes one more
+// cycle, or two if the branch crosses a page as PC's high byte must be fixed.
+//
+// Interrupts are not polled during the extra cycle of a branch that doesn't
+// cross a page, so an interrupt happening then is delayed by one instruction.
 func (cpu *CPU) branchTo(addr uint16) {
+	pending := cpu.pending
+	cpu.read(cpu.PC) // dummy read of the next opcode
 	if cpu.PC&0xff00 != addr&0xff00 {
-		// different page
-		cpu.cyc += 2
+		// different page, read from the address before the high byte is fixed
+		cpu.read(cpu.PC&0xff00 | addr&0xff)
 	} else {
-		cpu.cyc += 1
+		cpu.pending = pending
 	}
 	cpu.PC = addr
 }
@@ -72,11 +78,16 @@ func jmp(cpu *CPU, am AddressMode) {
 }
 
 func jsr(cpu *CPU, am AddressMode) {
-	addr := am.Addr(cpu)
-	cpu.Push16(cpu.PC - 1) // push PC+2 (we are at PC+3 now)
-	cpu.PC = addr
+	// the high byte of the address is read after pushing PC
+	lo := cpu.ReadPC()
+	cpu.dummyPull()
+	cpu.Push16(cpu.PC) // push PC+2, the address of the high byte
+	hi := cpu.read(cpu.PC)
+	cpu.PC = uint16(lo) | uint16(hi)<<8
 }
 
 func rts(cpu *CPU, am AddressMode) {
-	cpu.PC = cpu.Pull16() + 1
+	cpu.dummyPull()
+	cpu.PC = cpu.Pull16()
+	cpu.ReadPC() // dummy read while PC is incremented
 }
@@ -0,0 +1,157 @@
+package cpu6502
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/MagicalTux/gones/memory"
+)
+
+// recordCPU returns a 2A03 running prog at $0200 with 64 KB of RAM holding
+// the values of mem, and the list where its bus accesses are recorded
+func recordCPU(prog []byte, mem map[uint16]byte) (*CPU, *[]memory.Access) {
+	cpu, _ := testCPU(Variant2A03, prog...)
+	bus := cpu.Memory.(*memory.Bus)
+	bus.MapHandler(0x8000, 0x8000, memory.NewRAM(0x8000))
+	for addr, v := range mem {
+		bus.MemWrite(addr, v)
+	}
+
+	log := &[]memory.Access{}
+	bus.AddWatch(&memory.Watch{Start: 0, End: 0xffff, Read: true, Write: true, Func: func(a memory.Access) {
+		*log = append(*log, a)
+	}})
+	return cpu, log
+}
+
+// accesses formats a list of accesses as "r $0200=$a9, w $0100=$00, ..."
+func accesses(log []memory.Access) string {
+	s := make([]string, len(log))
+	for n, a := range log {
+		k := "r"
+		if a.Write {
+			k = "w"
+		}
+		s[n] = fmt.Sprintf("%s $%04x=$%02x", k, a.Addr, a.Value)
+	}
+	return strings.Join(s, ", ")
+}
+
+func TestBusAccesses(t *testing.T) {
+	tests := []struct {
+		name string
+		prog []byte
+		mem  map[uint16]byte
+		x    byte
+		want string
+	}{
+		{
+			name: "LDA abs,X",
+			prog: []byte{0xbd, 0x10, 0x12}, x: 0x20,
+			mem:  map[uint16]byte{0x1230: 0x42},
+			want: "r $0200=$bd, r $0201=$10, r $0202=$12, r $1230=$42",
+		},
+		{
+			// the high byte of the address is fixed after a first read
+			name: "LDA abs,X page cross",
+			prog: []byte{0xbd, 0xf0, 0x12}, x: 0x20,
+			mem:  map[uint16]byte{0x1210: 0x99, 0x1310: 0x42},
+			want: "r $0200=$bd, r $0201=$f0, r $0202=$12, r $1210=$99, r $1310=$42",
+		},
+		{
+			// writes always read the address before fixing its high byte
+			name: "STA abs,X",
+			prog: []byte{0x9d, 0x10, 0x12}, x: 0x20,
+			want: "r $0200=$9d, r $0201=$10, r $0202=$12, r $1230=$00, w $1230=$00",
+		},
+		{
+			// read-modify-write instructions write the value back unmodified first
+			name: "INC zpg",
+			prog: []byte{0xe6, 0x10},
+			mem:  map[uint16]byte{0x0010: 0x41},
+			want: "r $0200=$e6, r $0201=$10, r $0010=$41, w $0010=$41, w $0010=$42",
+		},
+		{
+			name: "ASL abs,X",
+			prog: []byte{0x1e, 0xf0, 0x12}, x: 0x20,
+			mem:  map[uint16]byte{0x1310: 0x21},
+			want: "r $0200=$1e, r $0201=$f0, r $0202=$12, r $1210=$00, r $1310=$21, w $1310=$21, w $1310=$42",
+		},
+		{
+			// the high byte of the target is read after pushing PC
+			name: "JSR",
+			prog: []byte{0x20, 0x34, 0x12},
+			want: "r $0200=$20, r $0201=$34, r $01fd=$00, w $01fd=$02, w $01fc=$02, r $0202=$12",
+		},
+		{
+			name: "RTS",
+			prog: []byte{0x60},
+			mem:  map[uint16]byte{0x01fe: 0x33, 0x01ff: 0x12},
+			want: "r $0200=$60, r $0201=$00, r $01fd=$00, r $01fe=$33, r $01ff=$12, r $1233=$00",
+		},
+		{
+			name: "BRK",
+			prog: []byte{0x00, 0xff},
+			mem:  map[uint16]byte{0xfffe: 0x00, 0xffff: 0x90},
+			want: "r $0200=$00, r $0201=$ff, w $01fd=$02, w $01fc=$02, w $01fb=$34, r $fffe=$00, r $ffff=$90",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cpu, log := recordCPU(tt.prog, tt.mem)
+			cpu.X = tt.x
+
+			cyc := cpu.Clock(0)
+			if got := accesses(*log); got != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+			if int(cyc) != len(*log) {
+				t.Errorf("took %d cycles for %d accesses", cyc, len(*log))
+			}
+		})
+	}
+}
+
+func TestCycles(t *testing.T) {
+	tests := []struct {
+		name string
+		prog []byte
+		x, y byte
+		cyc  uint64
+	}{
+		{"NOP", []byte{0xea}, 0, 0, 2},
+		{"LDA #", []byte{0xa9, 0x01}, 0, 0, 2},
+		{"LDA zpg", []byte{0xa5, 0x10}, 0, 0, 3},
+		{"LDA zpg,X", []byte{0xb5, 0x10}, 1, 0, 4},
+		{"LDA abs", []byte{0xad, 0x00, 0x12}, 0, 0, 4},
+		{"LDA abs,Y", []byte{0xb9, 0x00, 0x12}, 0, 0xff, 4},
+		{"LDA abs,Y page cross", []byte{0xb9, 0x01, 0x12}, 0, 0xff, 5},
+		{"LDA (ind,X)", []byte{0xa1, 0x10}, 1, 0, 6},
+		{"LDA (ind),Y", []byte{0xb1, 0x10}, 0, 1, 5},
+		{"STA (ind),Y", []byte{0x91, 0x10}, 0, 1, 6},
+		{"INC abs,X", []byte{0xfe, 0x00, 0x12}, 1, 0, 7},
+		{"PHA", []byte{0x48}, 0, 0, 3},
+		{"PLA", []byte{0x68}, 0, 0, 4},
+		{"JMP abs", []byte{0x4c, 0x00, 0x12}, 0, 0, 3},
+		{"JMP (ind)", []byte{0x6c, 0x00, 0x12}, 0, 0, 5},
+		{"RTI", []byte{0x40}, 0, 0, 6},
+		{"BNE not taken", []byte{0xd0, 0x10}, 0, 0, 2},
+		{"BEQ taken", []byte{0xf0, 0x10}, 0, 0, 3},
+		{"BEQ taken page cross", []byte{0xf0, 0x80}, 0, 0, 4},
+		{"SLO abs,X", []byte{0x1f, 0x00, 0x12}, 1, 0, 7},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cpu, _ := recordCPU(tt.prog, nil)
+			cpu.X, cpu.Y = tt.x, tt.y
+			cpu.P |= FlagZero
+
+			if cyc := cpu.Clock(0); cyc != tt.cyc {
+				t.Errorf("took %d cycles, want %d", cyc, tt.cyc)
+			}
+		})
+	}
+}
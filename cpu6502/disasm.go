@@ -0,0 +1,261 @@
+package cpu6502
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Instruction is a disassembled instruction
+type Instruction struct {
+	Addr     uint16      // address of the instruction
+	Bytes    []byte      // raw bytes, opcode first
+	Mnemonic string      // mnemonic, such as "LDA", or ".byte" if the instruction is incomplete
+	Mode     AddressMode // addressing mode
+	Operand  uint16      // operand, for relative branches this is the target address
+	Cycles   int         // base number of cycles
+	Illegal  bool        // true if this is an undocumented opcode
+}
+
+// official opcodes, everything else is an undocumented (illegal) opcode
+// see: https://www.masswerk.at/6502/6502_instruction_set.html
+var officialMnemonics = map[string]bool{
+	"ADC": true, "AND": true, "ASL": true, "BCC": true, "BCS": true, "BEQ": true, "BIT": true, "BMI": true,
+	"BNE": true, "BPL": true, "BRK": true, "BVC": true, "BVS": true, "CLC": true, "CLD": true, "CLI": true,
+	"CLV": true, "CMP": true, "CPX": true, "CPY": true, "DEC": true, "DEX": true, "DEY": true, "EOR": true,
+	"INC": true, "INX": true, "INY": true, "JMP": true, "JSR": true, "LDA": true, "LDX": true, "LDY": true,
+	"LSR": true, "NOP": true, "ORA": true, "PHA": true, "PHP": true, "PLA": true, "PLP": true, "ROL": true,
+	"ROR": true, "RTI": true, "RTS": true, "SBC": true, "SEC": true, "SED": true, "SEI": true, "STA": true,
+	"STX": true, "STY": true, "TAX": true, "TAY": true, "TSX": true, "TXA": true, "TXS": true, "TYA": true,
+}
+
+// instructions added by the 65C02, RMB, SMB, BBR and BBS are followed by a
+// bit number
+var cmosMnemonics = map[string]bool{
+	"BRA": true, "PHX": true, "PHY": true, "PLX": true, "PLY": true, "STZ": true, "TRB": true, "TSB": true,
+	"RMB": true, "SMB": true, "BBR": true, "BBS": true, "WAI": true, "STP": true,
+}
+
+// IsIllegal returns true if the given opcode is not one of the 151 official
+// opcodes of the 6502
+func IsIllegal(opcode byte) bool {
+	return Variant2A03.IsIllegal(opcode)
+}
+
+// IsIllegal returns true if the given opcode is not one of the official
+// opcodes of the variant
+func (v Variant) IsIllegal(opcode byte) bool {
+	o := v.ops()[opcode]
+	if o == nil {
+		return true
+	}
+	if !officialMnemonics[o.i] && !(v == Variant65C02 && cmosMnemonics[o.i[:3]]) {
+		return true
+	}
+	// only $EA is the official NOP, others are undocumented with various addressing modes
+	return o.i == "NOP" && opcode != 0xea
+}
+
+// Disassemble disassembles code, which is located at base in memory. If the
+// last instruction is incomplete, its bytes are returned as ".byte".
+func Disassemble(code []byte, base uint16) []*Instruction {
+	return Variant2A03.Disassemble(code, base)
+}
+
+// Disassemble is like the Disassemble function, using the opcodes of the
+// variant
+func (v Variant) Disassemble(code []byte, base uint16) []*Instruction {
+	var res []*Instruction
+
+	for pos := 0; pos < len(code); {
+		i := v.DisassembleOne(code[pos:], base+uint16(pos))
+		res = append(res, i)
+		pos += len(i.Bytes)
+	}
+	return res
+}
+
+// DisassembleOne disassembles the first instruction of code, located at addr
+func DisassembleOne(code []byte, addr uint16) *Instruction {
+	return Variant2A03.DisassembleOne(code, addr)
+}
+
+// DisassembleOne is like the DisassembleOne function, using the opcodes of
+// the variant
+func (v Variant) DisassembleOne(code []byte, addr uint16) *Instruction {
+	if len(code) == 0 {
+		return nil
+	}
+	o := v.ops()[code[0]]
+	ln := 1 + o.am.Length()
+	if ln > len(code) {
+		return &Instruction{Addr: addr, Bytes: code[:1], Mnemonic: ".byte", Mode: amImmed, Operand: uint16(code[0])}
+	}
+
+	i := &Instruction{
+		Addr:     addr,
+		Bytes:    code[:ln],
+		Mnemonic: o.i,
+		Mode:     o.am,
+		Cycles:   int(o.cyc),
+		Illegal:  v.IsIllegal(code[0]),
+	}
+	switch ln {
+	case 2:
+		i.Operand = uint16(code[1])
+	case 3:
+		i.Operand = uint16(code[1]) | uint16(code[2])<<8
+	}
+	switch o.am {
+	case amRel:
+		// target of the branch
+		i.Operand = addr + 2 + uint16(int8(code[1]))
+	case amZpgRel:
+		// target of the branch, the zeropage address is in Bytes[1]
+		i.Operand = addr + 3 + uint16(int8(code[2]))
+	}
+	return i
+}
+
+// Len returns the length of the instruction in bytes
+func (i *Instruction) Len() int {
+	return len(i.Bytes)
+}
+
+// HasAddress returns true if the operand of the instruction is a memory
+// address (as opposed to an immediate value, or no operand)
+func (i *Instruction) HasAddress() bool {
+	switch i.Mode {
+	case amAbs, amAbsX, amAbsY, amInd, amIndX, amIndY, amRel, amZpg, amZpgX, amZpgY, amZpgInd, amAbsIndX, amZpgRel:
+		return i.Mnemonic != ".byte"
+	default:
+		return false
+	}
+}
+
+// Target returns the address a jump, call or branch continues at, and false
+// if the instruction isn't one or its target isn't known before running it
+// (such as JMP ($1234))
+func (i *Instruction) Target() (uint16, bool) {
+	switch i.Mnemonic {
+	case "JMP", "JSR":
+		return i.Operand, i.Mode == amAbs
+	}
+	switch i.Mode {
+	case amRel, amZpgRel:
+		return i.Operand, true
+	default:
+		return 0, false
+	}
+}
+
+// String returns the instruction in standard assembler syntax, such as
+// "LDA ($12),Y". Undocumented opcodes are prefixed with a *.
+func (i *Instruction) String() string {
+	return i.Format(nil)
+}
+
+// Format returns the instruction like String, using label (if not nil) to
+// give names to addresses. label should return an empty string for addresses
+// without a name.
+func (i *Instruction) Format(label func(addr uint16) string) string {
+	if i.Mnemonic == ".byte" {
+		return fmt.Sprintf(".byte $%02x", i.Operand)
+	}
+
+	var addr string
+	if i.HasAddress() {
+		if label != nil {
+			addr = label(i.Operand)
+		}
+		if addr == "" {
+			if i.Len() == 2 && i.Mode != amRel {
+				addr = fmt.Sprintf("$%02x", i.Operand)
+			} else {
+				addr = fmt.Sprintf("$%04x", i.Operand)
+			}
+		}
+	}
+
+	var op string
+	switch i.Mode {
+	case amAcc:
+		op = "A"
+	case amImmed:
+		op = fmt.Sprintf("#$%02x", i.Operand)
+	case amAbs, amZpg, amRel:
+		op = addr
+	case amAbsX, amZpgX:
+		op = addr + ",X"
+	case amAbsY, amZpgY:
+		op = addr + ",Y"
+	case amInd:
+		op = "(" + addr + ")"
+	case amIndX:
+		op = "(" + addr + ",X)"
+	case amIndY:
+		op = "(" + addr + "),Y"
+	case amZpgInd:
+		op = "(" + addr + ")"
+	case amAbsIndX:
+		op = "(" + addr + ",X)"
+	case amZpgRel:
+		op = fmt.Sprintf("$%02x,", i.Bytes[1]) + addr
+	}
+
+	res := i.Mnemonic
+	if i.Illegal {
+		res = "*" + res
+	}
+	if op != "" {
+		res += " " + op
+	}
+	return res
+}
+
+// Hex returns the bytes of the instruction in hexadecimal, such as "8D 00 20"
+func (i *Instruction) Hex() string {
+	s := make([]string, len(i.Bytes))
+	for n, b := range i.Bytes {
+		s[n] = fmt.Sprintf("%02X", b)
+	}
+	return strings.Join(s, " ")
+}
+
+func (am AddressMode) String() string {
+	switch am {
+	case amAcc:
+		return "acc"
+	case amAbs:
+		return "abs"
+	case amAbsX:
+		return "abs,X"
+	case amAbsY:
+		return "abs,Y"
+	case amImmed:
+		return "imm"
+	case amImpl:
+		return "impl"
+	case amInd:
+		return "ind"
+	case amIndX:
+		return "ind,X"
+	case amIndY:
+		return "ind,Y"
+	case amRel:
+		return "rel"
+	case amZpg:
+		return "zpg"
+	case amZpgX:
+		return "zpg,X"
+	case amZpgY:
+		return "zpg,Y"
+	case amZpgInd:
+		return "(zpg)"
+	case amAbsIndX:
+		return "(abs,X)"
+	case amZpgRel:
+		return "zpg,rel"
+	default:
+		return fmt.Sprintf("AddressMode(%d)", byte(am))
+	}
+}
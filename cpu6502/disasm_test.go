@@ -0,0 +1,75 @@
+package cpu6502
+
+import "testing"
+
+func TestDisassemble(t *testing.T) {
+	type line struct {
+		addr    uint16
+		text    string
+		target  int // -1 if the instruction has no known target
+		illegal bool
+	}
+
+	tests := []struct {
+		name    string
+		variant Variant
+		code    []byte
+		base    uint16
+		want    []line
+	}{
+		{
+			name:    "2A03",
+			variant: Variant2A03,
+			// start of nestest's automated mode, and a few more
+			code: []byte{0xa2, 0x00, 0x86, 0x00, 0x20, 0x2d, 0xc7, 0x6c, 0x00, 0x02, 0xd0, 0xfe, 0xa7, 0x10, 0x4c, 0xf5, 0xc5, 0xb1, 0x20, 0xad},
+			base: 0xc5f5,
+			want: []line{
+				{0xc5f5, "LDX #$00", -1, false},
+				{0xc5f7, "STX $00", -1, false},
+				{0xc5f9, "JSR $c72d", 0xc72d, false},
+				{0xc5fc, "JMP ($0200)", -1, false},
+				{0xc5ff, "BNE $c5ff", 0xc5ff, false},
+				{0xc601, "*LAX $10", -1, true},
+				{0xc603, "JMP $c5f5", 0xc5f5, false},
+				{0xc606, "LDA ($20),Y", -1, false},
+				{0xc608, ".byte $ad", -1, false},
+			},
+		},
+		{
+			name:    "65C02",
+			variant: Variant65C02,
+			code:    []byte{0x80, 0x02, 0x0f, 0x12, 0xfd, 0x04, 0x10, 0xb2, 0x20, 0x9c, 0x00, 0x02, 0x7c, 0x00, 0x80, 0xa7, 0x10, 0x03},
+			base:    0x8000,
+			want: []line{
+				{0x8000, "BRA $8004", 0x8004, false},
+				{0x8002, "BBR0 $12,$8002", 0x8002, false},
+				{0x8005, "TSB $10", -1, false},
+				{0x8007, "LDA ($20)", -1, false},
+				{0x8009, "STZ $0200", -1, false},
+				{0x800c, "JMP ($8000,X)", -1, false},
+				{0x800f, "SMB2 $10", -1, false},
+				{0x8011, "*NOP", -1, true},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ins := tt.variant.Disassemble(tt.code, tt.base)
+			if len(ins) != len(tt.want) {
+				t.Fatalf("got %d instructions, want %d", len(ins), len(tt.want))
+			}
+			for n, i := range ins {
+				w := tt.want[n]
+				target, ok := i.Target()
+				if !ok {
+					target = 0
+				}
+				if i.Addr != w.addr || i.String() != w.text || ok != (w.target != -1) || ok && int(target) != w.target || i.Illegal != w.illegal {
+					t.Errorf("got $%04x %q target=$%04x,%v illegal=%v, want $%04x %q target=%d illegal=%v",
+						i.Addr, i, target, ok, i.Illegal, w.addr, w.text, w.target, w.illegal)
+				}
+			}
+		})
+	}
+}
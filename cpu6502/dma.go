@@ -0,0 +1,99 @@
+package cpu6502
+
+// dma is the 2A03's DMA unit, which copies sprites to the PPU (OAM DMA) and
+// fetches samples for the APU's DMC channel. It halts the CPU on its next
+// read cycle and then uses the bus, alternating between get (read) and put
+// (write) cycles. While halted, the CPU keeps reading the address it was
+// about to read, which causes the extra reads of registers such as $2007.
+// See: https://www.nesdev.org/wiki/DMA
+type dma struct {
+	oam     bool   // OAM DMA pending or running
+	oamPage byte   // page to copy
+	oamPos  uint16 // next byte to copy
+	oamVal  byte   // byte read on the last get cycle
+	oamGot  bool   // oamVal needs to be written on the next put cycle
+
+	dmc     bool   // DMC DMA pending or running
+	dmcAddr uint16 // address of the sample byte
+	dmcWait int    // halt and dummy cycles left before the sample can be read
+
+	running bool // runDMA is running
+}
+
+// StartOAMDMA starts copying the 256 bytes of the given page to the PPU's
+// OAMDATA register ($2004). The copy starts on the next read cycle of the
+// CPU, and takes 513 or 514 cycles.
+func (cpu *CPU) StartOAMDMA(page byte) {
+	cpu.dma.oam = true
+	cpu.dma.oamPage = page
+	cpu.dma.oamPos = 0
+	cpu.dma.oamGot = false
+}
+
+// StartDMCDMA starts fetching a sample byte for the DMC channel at addr,
+// which is passed to DMCDone. The fetch takes 3 or 4 cycles starting from the
+// next read cycle of the CPU, usually 2 if an OAM DMA is running.
+func (cpu *CPU) StartDMCDMA(addr uint16) {
+	cpu.dma.dmc = true
+	cpu.dma.dmcAddr = addr
+	cpu.dma.dmcWait = 2
+}
+
+// active returns true if a transfer is pending or running
+func (d *dma) active() bool {
+	return d.oam || d.dmc
+}
+
+// runDMA runs pending DMA transfers. It is called when the CPU is about to
+// read addr, and returns once the CPU can resume.
+func (cpu *CPU) runDMA(addr uint16) {
+	d := &cpu.dma
+	d.running = true
+	defer func() { d.running = false }()
+
+	// the CPU is halted on this cycle, and its read happens anyway
+	cpu.read(addr)
+	repeat := true // the CPU address was read on the previous cycle
+
+	for d.active() {
+		if d.dmcWait > 0 {
+			d.dmcWait -= 1
+		}
+		get := cpu.cyc&1 == 0
+
+		switch {
+		case get && d.dmc && d.dmcWait == 0:
+			v := cpu.read(d.dmcAddr)
+			d.dmc = false
+			if f := cpu.DMCDone; f != nil {
+				f(v)
+			}
+			repeat = false
+		case get && d.oam && !d.oamGot:
+			d.oamVal = cpu.read(uint16(d.oamPage)<<8 | d.oamPos)
+			d.oamGot = true
+			repeat = false
+		case !get && d.oamGot:
+			cpu.write(0x2004, d.oamVal)
+			d.oamGot = false
+			d.oamPos += 1
+			if d.oamPos == 256 {
+				d.oam = false
+			}
+			repeat = false
+		case repeat && (addr == 0x4016 || addr == 0x4017):
+			// dummy or alignment cycle on the controller ports, which only
+			// see one read when read on consecutive cycles
+			cpu.sync()
+			cpu.poll()
+			cpu.cyc += 1
+		default:
+			// dummy or alignment cycle, the CPU address is read again
+			cpu.read(addr)
+			repeat = true
+		}
+	}
+
+	// catch up with the cycle of the CPU's read
+	cpu.sync()
+}
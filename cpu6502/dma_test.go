@@ -0,0 +1,129 @@
+package cpu6502
+
+import (
+	"testing"
+
+	"github.com/MagicalTux/gones/memory"
+)
+
+// oamPage fills page 3 with a pattern to be copied by OAM DMA
+func oamPage() map[uint16]byte {
+	mem := map[uint16]byte{}
+	for i := 0; i < 256; i++ {
+		mem[0x300+uint16(i)] = byte(i*3 + 1)
+	}
+	return mem
+}
+
+// oamWrites returns the values written to OAMDATA
+func oamWrites(log []memory.Access) []byte {
+	var res []byte
+	for _, a := range log {
+		if a.Write && a.Addr == 0x2004 {
+			res = append(res, a.Value)
+		}
+	}
+	return res
+}
+
+func TestOAMDMA(t *testing.T) {
+	tests := []struct {
+		name string
+		cyc  uint64 // CPU cycle when the DMA starts
+		dma  uint64 // cycles taken by the DMA
+	}{
+		{"put cycle", 1, 513},
+		{"get cycle", 2, 514},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cpu, log := recordCPU([]byte{0xea}, oamPage())
+			cpu.cyc = tt.cyc
+			cpu.StartOAMDMA(0x03)
+
+			// NOP takes 2 cycles, the DMA starts on its first read
+			if cyc := cpu.Clock(0); cyc != 2+tt.dma {
+				t.Errorf("took %d cycles, want %d", cyc, 2+tt.dma)
+			}
+			w := oamWrites(*log)
+			if len(w) != 256 {
+				t.Fatalf("%d writes to OAMDATA, want 256", len(w))
+			}
+			for i, v := range w {
+				if v != byte(i*3+1) {
+					t.Fatalf("write %d is $%02x, want $%02x", i, v, byte(i*3+1))
+				}
+			}
+			if cpu.PC != 0x201 {
+				t.Errorf("PC = $%04x after the DMA, want $0201", cpu.PC)
+			}
+		})
+	}
+}
+
+func TestDMCDuringOAMDMA(t *testing.T) {
+	mem := oamPage()
+	mem[0x8123] = 0x5a
+	cpu, log := recordCPU([]byte{0xea}, mem)
+	cpu.cyc = 1
+
+	var sample []byte
+	cpu.DMCDone = func(v byte) { sample = append(sample, v) }
+	cpu.Sync = func(n uint64) {
+		if n == 100 {
+			cpu.StartDMCDMA(0x8123)
+		}
+	}
+	cpu.StartOAMDMA(0x03)
+
+	// the DMC fetch steals 2 cycles from the OAM DMA
+	if cyc := cpu.Clock(0); cyc != 2+513+2 {
+		t.Errorf("took %d cycles, want %d", cyc, 2+513+2)
+	}
+	if len(sample) != 1 || sample[0] != 0x5a {
+		t.Errorf("DMC got %v, want [$5a]", sample)
+	}
+	if w := oamWrites(*log); len(w) != 256 {
+		t.Errorf("%d writes to OAMDATA, want 256", len(w))
+	}
+}
+
+func TestDMCDMAHaltReads(t *testing.T) {
+	tests := []struct {
+		name  string
+		addr  uint16
+		reads int // reads of addr by the instruction
+	}{
+		// the controller ports only see consecutive reads as one, the DMC read
+		// in the middle splits them in two
+		{"controller port", 0x4016, 2},
+		// other addresses are read again on the dummy cycle
+		{"RAM", 0x0400, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// LDA addr
+			cpu, log := recordCPU([]byte{0xad, byte(tt.addr), byte(tt.addr >> 8)}, nil)
+			cpu.cyc = 1
+			cpu.Sync = func(n uint64) {
+				if n == 3 {
+					// halt on the read of addr
+					cpu.StartDMCDMA(0x8000)
+				}
+			}
+			cpu.Clock(0)
+
+			reads := 0
+			for _, a := range *log {
+				if !a.Write && a.Addr == tt.addr {
+					reads++
+				}
+			}
+			if reads != tt.reads {
+				t.Errorf("%d reads of $%04x, want %d", reads, tt.addr, tt.reads)
+			}
+		})
+	}
+}
@@ -6,11 +6,11 @@ func slo(cpu *CPU, am AddressMode) {
 	//Flags: N Z C
 
 	addr := am.AddrFast(cpu)
-	v := cpu.Memory.MemRead(addr) // input M or 0 ?
+	v := cpu.readModify(addr) // input M or 0 ?
 
 	cpu.setFlag(FlagCarry, v&0x80 == 0x80)
 	v <<= 1
-	cpu.Memory.MemWrite(addr, v)
+	cpu.write(addr, v)
 	cpu.flagsNZ(v)
 
 	// ORA
@@ -32,11 +32,11 @@ func rla(cpu *CPU, am AddressMode) {
 	}
 
 	addr := am.AddrFast(cpu)
-	v := cpu.Memory.MemRead(addr)
+	v := cpu.readModify(addr)
 
 	cpu.setFlag(FlagCarry, v&0x80 == 0x80)
 	v = (v << 1) | c
-	cpu.Memory.MemWrite(addr, v)
+	cpu.write(addr, v)
 
 	cpu.A &= v
 	cpu.flagsNZ(cpu.A)
@@ -48,11 +48,11 @@ func sre(cpu *CPU, am AddressMode) {
 	// Flags: N Z C
 
 	addr := am.AddrFast(cpu)
-	v := cpu.Memory.MemRead(addr)
+	v := cpu.readModify(addr)
 
 	cpu.setFlag(FlagCarry, v&1 == 1)
 	v >>= 1
-	cpu.Memory.MemWrite(addr, v)
+	cpu.write(addr, v)
 
 	cpu.A ^= v
 	cpu.flagsNZ(cpu.A)
@@ -69,22 +69,14 @@ func rra(cpu *CPU, am AddressMode) {
 
 	// act on mem
 	addr := am.AddrFast(cpu)
-	v := cpu.Memory.MemRead(addr)
+	v := cpu.readModify(addr)
 
-	c2 := v & 1
 	cpu.setFlag(FlagCarry, v&1 == 1)
 	v = (v >> 1) | (c << 7)
-	cpu.Memory.MemWrite(addr, v)
+	cpu.write(addr, v)
 
-	// ADC
-	c = c2
-	a := cpu.A
-	cpu.A = a + v + c
-
-	cpu.flagsNZ(cpu.A)
-
-	cpu.setFlag(FlagCarry, int(a)+int(v)+int(c) > 0xff)
-	cpu.setFlag(FlagOverflow, (a^v)&0x80 == 0 && (a^cpu.A)&0x80 != 0)
+	// ADC, with the carry from ROR
+	cpu.add(v)
 }
 
 func anc(cpu *CPU, am AddressMode) {
@@ -122,7 +114,7 @@ func alr(cpu *CPU, am AddressMode) {
 
 	cpu.setFlag(FlagCarry, cpu.A&1 == 1)
 	cpu.A >>= 1
-	//cpu.Memory.MemWrite(addr, v) // can't set back value as it was an immed value
+	//cpu.write(addr, v) // can't set back value as it was an immed value
 	cpu.flagsNZ(cpu.A)
 }
 
@@ -216,7 +208,6 @@ func las(cpu *CPU, am AddressMode) {
 	cpu.A = v
 	cpu.X = v
 	cpu.S = v
-	cpu.Memory.MemWrite(0x100+uint16(cpu.S), v)
 	cpu.flagsNZ(v)
 }
 
@@ -228,7 +219,7 @@ func sha(cpu *CPU, am AddressMode) {
 
 	addr := am.AddrFast(cpu)
 	v := cpu.A & cpu.X & uint8(addr>>8)
-	cpu.Memory.MemWrite(addr, v)
+	cpu.write(addr, v)
 }
 
 func sbx(cpu *CPU, am AddressMode) {
@@ -259,7 +250,7 @@ func shy(cpu *CPU, am AddressMode) {
 	// Y AND (H+1) -> M
 
 	addr := am.AddrFast(cpu)
-	cpu.Memory.MemWrite(addr, cpu.Y&uint8((addr>>8)+1))
+	cpu.write(addr, cpu.Y&uint8((addr>>8)+1))
 }
 
 func tas(cpu *CPU, am AddressMode) {
@@ -271,7 +262,7 @@ func tas(cpu *CPU, am AddressMode) {
 
 	addr := am.AddrFast(cpu)
 	cpu.S = cpu.A & cpu.X
-	cpu.Memory.MemWrite(addr, cpu.A&cpu.X&uint8((addr>>8)+1))
+	cpu.write(addr, cpu.A&cpu.X&uint8((addr>>8)+1))
 }
 
 func shx(cpu *CPU, am AddressMode) {
@@ -282,5 +273,5 @@ func shx(cpu *CPU, am AddressMode) {
 	// X AND (H+1) -> M
 
 	addr := am.AddrFast(cpu)
-	cpu.Memory.MemWrite(addr, cpu.X&uint8((addr>>8)+1))
+	cpu.write(addr, cpu.X&uint8((addr>>8)+1))
 }
@@ -2,60 +2,88 @@ package cpu6502
 
 import "log"
 
+// IRQSource is a device that can drive the IRQ line. The line is level
+// triggered: it stays asserted as long as any source asserts it, and each
+// source acknowledges its own interrupt.
+type IRQSource byte
+
 const (
-	InterruptNone = iota // 0
-	InterruptIRQ
-	InterruptNMI
+	IRQFrameCounter IRQSource = 1 << iota // APU frame counter
+	IRQDMC                                // APU DMC channel
+	IRQMapper                             // cartridge mapper, such as MMC3's scanline counter
 )
 
+// NMI signals an edge on the NMI line. The NMI is taken after the current
+// instruction if it happened before its second to last cycle, or after the
+// next one otherwise.
 func (cpu *CPU) NMI() {
-	cpu.interrupt = InterruptNMI
+	cpu.nmi = true
 }
 
-func (cpu *CPU) IRQ() {
-	if cpu.interrupt < InterruptIRQ {
-		cpu.interrupt = InterruptIRQ
+// SetIRQ asserts or releases the IRQ line for the given source
+func (cpu *CPU) SetIRQ(src IRQSource, asserted bool) {
+	if asserted {
+		cpu.irq |= src
+	} else {
+		cpu.irq &^= src
 	}
 }
 
-func (cpu *CPU) handleInterrupt(i byte) {
-	// handle interrupt
-	cpu.Push16(cpu.PC)
-	cpu.Push(cpu.P) // | FlagBreak)
+// IRQ returns the sources currently asserting the IRQ line
+func (cpu *CPU) IRQ() IRQSource {
+	return cpu.irq
+}
 
-	switch i {
-	case InterruptNMI:
-		cpu.PC = cpu.Read16(NMIVector) // NMI interrupt vector
-	case InterruptIRQ:
-		cpu.PC = cpu.Read16(IRQVector) // IRQ interrupt vector
-	}
-	cpu.setFlag(FlagInterruptDisable, true)
-	cpu.cyc += 7
+// poll checks the interrupt lines. It is called on each cycle, and the
+// result of the last cycle of an instruction (which sees the state of the
+// lines at the end of the second to last cycle) decides if an interrupt is
+// run before the next instruction. Instructions that change the I flag do so
+// after their last cycle, which delays the effect of CLI, SEI and PLP by one
+// instruction.
+// See: https://www.nesdev.org/wiki/CPU_interrupts
+func (cpu *CPU) poll() {
+	cpu.pending = cpu.nmi || (cpu.irq != 0 && cpu.P&FlagInterruptDisable == 0)
 }
 
-func (cpu *CPU) SetNMI(v byte) {
-	cpu.nmiSig = v
+// handleInterrupt runs the 7 cycles of a hardware interrupt
+func (cpu *CPU) handleInterrupt() {
+	// the CPU reads the next opcode twice but ignores it
+	cpu.read(cpu.PC)
+	cpu.read(cpu.PC)
+	cpu.interrupt(cpu.P&^FlagBreak | FlagIgnored)
 }
 
-func (cpu *CPU) checkPendingNMI() {
-	if cpu.nmiSig > 0 {
-		cpu.nmiSig -= 1
-		if cpu.nmiSig == 0 {
-			cpu.NMI()
-		}
+// interrupt pushes PC and p, and jumps to the IRQ/BRK vector, or to the NMI
+// vector if a NMI happened before p was pushed (NMI hijacking). The 65C02
+// also clears the D flag.
+func (cpu *CPU) interrupt(p byte) {
+	cpu.Push16(cpu.PC)
+	cpu.Push(p)
+
+	vector := uint16(IRQVector)
+	if cpu.nmi {
+		cpu.nmi = false
+		vector = NMIVector
+	}
+	cpu.setFlag(FlagInterruptDisable, true)
+	if cpu.variant == Variant65C02 {
+		cpu.setFlag(FlagDecimal, false)
 	}
+	cpu.PC = cpu.read16(vector)
+
+	// the first instruction of the handler always runs
+	cpu.pending = false
 }
 
 func brk(cpu *CPU, am AddressMode) {
-	cpu.PC += 1
-	cpu.Push16(cpu.PC)
-	cpu.Push(cpu.P | FlagBreak)
+	cpu.ReadPC() // padding byte
 
-	cpu.PC = cpu.Read16(IRQVector) // IRQ/BRK
-	cpu.setFlag(FlagInterruptDisable, true)
+	// if hijacked by a NMI, the NMI handler finds B set on the stack
+	cpu.interrupt(cpu.P | FlagBreak | FlagIgnored)
 }
 
 func rti(cpu *CPU, am AddressMode) {
+	cpu.dummyPull()
 	p := cpu.Pull()
 	p &= ^byte(0x30)  // ignore B and bit5
 	p |= cpu.P & 0x30 // load B and bit5 from P
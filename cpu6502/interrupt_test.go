@@ -0,0 +1,120 @@
+package cpu6502
+
+import "testing"
+
+// vectors points NMI to $9000 and IRQ/BRK to $A000
+var vectors = map[uint16]byte{0xfffa: 0x00, 0xfffb: 0x90, 0xfffe: 0x00, 0xffff: 0xa0}
+
+// step runs one step of cpu (an instruction or an interrupt sequence), and
+// checks PC afterwards
+func step(t *testing.T, cpu *CPU, pc uint16, what string) {
+	t.Helper()
+	cpu.Clock(0)
+	if cpu.PC != pc {
+		t.Fatalf("after %s: PC = $%04x, want $%04x", what, cpu.PC, pc)
+	}
+}
+
+func TestInterruptLatency(t *testing.T) {
+	t.Run("CLI", func(t *testing.T) {
+		// the IRQ is only taken after the instruction following CLI
+		cpu, _ := recordCPU([]byte{0x58, 0xea, 0xea}, vectors)
+		cpu.SetIRQ(IRQMapper, true)
+		step(t, cpu, 0x201, "CLI")
+		step(t, cpu, 0x202, "NOP")
+		step(t, cpu, 0xa000, "IRQ")
+	})
+
+	t.Run("SEI", func(t *testing.T) {
+		// an IRQ seen during SEI is still taken, with I set on the stack
+		cpu, log := recordCPU([]byte{0x78, 0xea}, vectors)
+		cpu.P &^= FlagInterruptDisable
+		cpu.SetIRQ(IRQMapper, true)
+		step(t, cpu, 0x201, "SEI")
+		step(t, cpu, 0xa000, "IRQ")
+		if p := (*log)[len(*log)-3]; !p.Write || p.Addr != 0x1fb || p.Value&FlagInterruptDisable == 0 {
+			t.Errorf("pushed %s, want P with I set", p)
+		}
+	})
+
+	t.Run("PLP", func(t *testing.T) {
+		// like CLI, clearing I with PLP is delayed by one instruction
+		mem := map[uint16]byte{0x1fe: FlagIgnored}
+		for k, v := range vectors {
+			mem[k] = v
+		}
+		cpu, _ := recordCPU([]byte{0x28, 0xea, 0xea}, mem)
+		cpu.SetIRQ(IRQMapper, true)
+		step(t, cpu, 0x201, "PLP")
+		step(t, cpu, 0x202, "NOP")
+		step(t, cpu, 0xa000, "IRQ")
+	})
+
+	t.Run("masked", func(t *testing.T) {
+		cpu, _ := recordCPU([]byte{0xea, 0xea}, vectors)
+		cpu.SetIRQ(IRQMapper, true)
+		step(t, cpu, 0x201, "NOP")
+		step(t, cpu, 0x202, "NOP")
+	})
+}
+
+func TestNMIHijack(t *testing.T) {
+	tests := []struct {
+		name   string
+		cycle  uint64 // cycle of BRK during which the NMI happens
+		vector uint16
+	}{
+		{"before push of P", 3, 0x9000},
+		{"after push of P", 5, 0xa000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cpu, log := recordCPU([]byte{0x00, 0x00}, vectors)
+			cpu.Sync = func(n uint64) {
+				if n == tt.cycle {
+					cpu.NMI()
+				}
+			}
+			step(t, cpu, tt.vector, "BRK")
+
+			// the handler always finds B set
+			if p := (*log)[4]; !p.Write || p.Addr != 0x1fb || p.Value&FlagBreak == 0 {
+				t.Errorf("pushed %s, want P with B set", p)
+			}
+		})
+	}
+}
+
+func TestIRQSources(t *testing.T) {
+	cpu, _ := recordCPU([]byte{0xea, 0xea, 0xea}, vectors)
+	cpu.P &^= FlagInterruptDisable
+
+	cpu.SetIRQ(IRQFrameCounter, true)
+	cpu.SetIRQ(IRQMapper, true)
+	if irq := cpu.IRQ(); irq != IRQFrameCounter|IRQMapper {
+		t.Fatalf("IRQ() = %#x, want frame counter and mapper", irq)
+	}
+
+	// acknowledging one source leaves the line asserted by the other
+	cpu.SetIRQ(IRQFrameCounter, false)
+	if irq := cpu.IRQ(); irq != IRQMapper {
+		t.Fatalf("IRQ() = %#x, want mapper", irq)
+	}
+	step(t, cpu, 0x201, "NOP")
+	step(t, cpu, 0xa000, "IRQ")
+
+	// releasing a source that isn't asserting does nothing
+	cpu.SetIRQ(IRQDMC, false)
+	if irq := cpu.IRQ(); irq != IRQMapper {
+		t.Fatalf("IRQ() = %#x, want mapper", irq)
+	}
+
+	// with all sources released, the line is free and nothing is taken
+	cpu, _ = recordCPU([]byte{0xea, 0xea, 0xea}, vectors)
+	cpu.P &^= FlagInterruptDisable
+	cpu.SetIRQ(IRQDMC, true)
+	cpu.SetIRQ(IRQDMC, false)
+	step(t, cpu, 0x201, "NOP")
+	step(t, cpu, 0x202, "NOP")
+}
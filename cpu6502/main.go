@@ -21,44 +21,84 @@ type CPU struct {
 	S    byte   // stack pointer
 	P    byte   // status register
 
-	Memory    memory.Master
-	fault     bool
-	interrupt byte
-	nmiSig    byte // NMI timer
-	Trace     io.Writer
+	Memory  memory.Master
+	variant Variant
+	fault   bool
+	wait    bool      // WAI instruction waiting for an interrupt
+	nmi     bool      // NMI edge detected
+	irq     IRQSource // sources asserting IRQ
+	pending bool      // interrupt to run before the next instruction, see poll
+	Trace   io.Writer
+
+	// TraceFormat is the format of lines written to Trace
+	TraceFormat TraceFormat
+
+	// TracePPU, if set, returns the PPU position included in traces using
+	// the TraceNestest format
+	TracePPU func() (scanline, dot uint16)
+
+	// Hook, if set, is called before each instruction, including the first
+	// instruction of interrupt handlers. If it returns false the instruction
+	// is not run and Clock returns without consuming any cycle, and the
+	// caller should stop the clock. Used by debuggers.
+	Hook func(cpu *CPU) bool
+
+	// Sync, if set, is called before each bus access with the number of
+	// cycles run since Clock was called, so the rest of the machine can catch
+	// up with the CPU before the access happens. See clock.Master.CatchUp.
+	Sync func(cycles uint64)
+
+	// DMCDone is called with the sample byte fetched by StartDMCDMA
+	DMCDone func(v byte)
+
+	cyc   uint64
+	start uint64 // value of cyc when Clock was called
+	dma   dma
+}
 
-	cyc    uint64
-	freeze uint64
+// New returns a CPU of the given variant
+func New(v Variant) *CPU {
+	return &CPU{variant: v}
 }
 
-func New() *CPU {
-	return new(CPU)
+// Variant returns the variant of the CPU
+func (cpu *CPU) Variant() Variant {
+	return cpu.variant
 }
 
 func (cpu *CPU) Clock(uint64) uint64 {
 	if cpu.fault {
 		return 9999
 	}
-	if cpu.freeze > 0 {
-		v := cpu.freeze
-		cpu.cyc += v
-		cpu.freeze = 0
-		return v
+	cpu.start = cpu.cyc
+
+	if cpu.wait {
+		if !cpu.nmi && cpu.irq == 0 {
+			// waiting for an interrupt, the bus is idle
+			cpu.cyc += 1
+			return 1
+		}
+		// the interrupt is only run if not masked
+		cpu.wait = false
+		cpu.poll()
 	}
 
-	cycstart := cpu.cyc
-
-	if cpu.interrupt == InterruptNMI || (cpu.interrupt == InterruptIRQ && !cpu.getFlag(FlagInterruptDisable)) {
-		cpu.handleInterrupt(cpu.interrupt)
-		cpu.interrupt = InterruptNone
+	if cpu.pending {
+		// the interrupt sequence is a step of its own, so that Hook sees the
+		// first instruction of the handler
+		cpu.handleInterrupt()
+		return cpu.cyc - cpu.start
 	}
 
-	cpu.checkPendingNMI()
+	if cpu.Hook != nil && !cpu.Hook(cpu) {
+		return 0
+	}
 
 	pos := cpu.PC
+	inscyc := cpu.cyc
 	// read value at PC
 	e := cpu.ReadPC()
-	o := cpu6502op[e]
+	o := cpu.variant.ops()[e]
 	if o == nil || o.f == nil {
 		cpu.fatal("FATAL CPU ERROR - unsupported op $%02x @ $%04x / %s", e, pos, cpu)
 		return 9999
@@ -66,28 +106,26 @@ func (cpu *CPU) Clock(uint64) uint64 {
 	//log.Printf("CPU Step: $%02x o=%v", e, o)
 	//log.Printf("CPU Step: [$%04x] %s %s", pos, o.i, o.am.Debug(cpu))
 	if cpu.Trace != nil {
-		fmt.Fprintf(cpu.Trace, "CPU Step cyc=%d: [$%04x] %s % -32s %s\n", cpu.cyc, pos, o.i, o.am.Debug(cpu), cpu)
+		cpu.trace(pos, inscyc, o)
 	}
 
-	o.f(cpu, o.am)
-
-	cpu.cyc += uint64(o.cyc)
-
-	if cpu.freeze > 0 {
-		cpu.cyc += cpu.freeze
-		cpu.freeze = 0
+	if (o.am == amImpl || o.am == amAcc) && o.cyc > 1 {
+		// single byte instructions still read the next byte, except the
+		// 65C02's single cycle NOPs
+		cpu.read(cpu.PC)
 	}
 
-	cycdelta := cpu.cyc - cycstart
+	o.f(cpu, o.am)
+
+	cycdelta := cpu.cyc - cpu.start
 
 	// number of cycles we've consumed in this run
 	return cycdelta
 }
 
-// TimeFreeze is used to "freeze" the CPU by a number of cycles, delaying the next operation
-func (cpu *CPU) TimeFreeze(v uint64) uint64 {
-	cpu.freeze += v
-	return cpu.freeze
+// Cycles returns the number of cycles run by the CPU since reset
+func (cpu *CPU) Cycles() uint64 {
+	return cpu.cyc
 }
 
 func (cpu *CPU) Reset() {
@@ -97,6 +135,9 @@ func (cpu *CPU) Reset() {
 	cpu.Y = 0
 	cpu.S = 0xfd
 	cpu.P = FlagIgnored | FlagInterruptDisable
+	cpu.nmi = false
+	cpu.pending = false
+	cpu.wait = false
 
 	cpu.cyc = 7 // cpu init typically takes 7 cycles
 
@@ -111,45 +152,104 @@ func (cpu *CPU) fatal(v string, a ...any) {
 	log.Printf("CPU FAULT: "+v, a...)
 }
 
+// read reads a byte from the bus, taking one cycle. Every cycle of the 6502
+// is either a read or a write, including the cycles where the value isn't
+// used (dummy reads), which matter for registers with read side effects.
+func (cpu *CPU) read(addr uint16) byte {
+	cpu.sync()
+	if cpu.dma.active() && !cpu.dma.running {
+		// DMA halts the CPU on read cycles
+		cpu.runDMA(addr)
+	}
+	cpu.poll()
+	v := cpu.Memory.MemRead(addr)
+	cpu.cyc += 1
+	return v
+}
+
+// write writes a byte to the bus, taking one cycle
+func (cpu *CPU) write(addr uint16, v byte) {
+	cpu.sync()
+	cpu.poll()
+	cpu.Memory.MemWrite(addr, v)
+	cpu.cyc += 1
+}
+
+// readModify reads the value at addr for a read-modify-write instruction,
+// which writes the value back unmodified on the next cycle while computing the
+// result.
+// See: https://www.nesdev.org/6502_cpu.txt → "Read-Modify-Write instructions"
+func (cpu *CPU) readModify(addr uint16) byte {
+	v := cpu.read(addr)
+	if cpu.variant == Variant65C02 {
+		// the 65C02 reads the value again instead
+		cpu.read(addr)
+	} else {
+		cpu.write(addr, v)
+	}
+	return v
+}
+
+// sync lets the rest of the machine catch up with the current cycle
+func (cpu *CPU) sync() {
+	if cpu.Sync != nil {
+		cpu.Sync(cpu.cyc - cpu.start)
+	}
+}
+
+// read16 reads a word, taking two cycles
+func (cpu *CPU) read16(addr uint16) uint16 {
+	lo := cpu.read(addr)
+	return uint16(lo) | uint16(cpu.read(addr+1))<<8
+}
+
+// ReadPC reads the byte at PC and increments PC, taking one cycle
 func (cpu *CPU) ReadPC() uint8 {
-	v := cpu.Memory.MemRead(cpu.PC)
+	v := cpu.read(cpu.PC)
 	cpu.PC += 1
 	return v
 }
 
+// ReadPC16 reads the word at PC and increments PC by 2, taking two cycles
 func (cpu *CPU) ReadPC16() uint16 {
-	v := cpu.Read16(cpu.PC)
-	cpu.PC += 2
-	return v
+	lo := cpu.ReadPC()
+	return uint16(lo) | uint16(cpu.ReadPC())<<8
 }
 
+// PeekPC returns the byte at PC without consuming cycles or other side
+// effects (see memory.Peek)
 func (cpu *CPU) PeekPC() uint8 {
-	return cpu.Memory.MemRead(cpu.PC)
+	return memory.Peek(cpu.Memory, cpu.PC)
 }
 
+// PeekPC16 returns the word at PC without consuming cycles or other side
+// effects
 func (cpu *CPU) PeekPC16() uint16 {
-	return cpu.Read16(cpu.PC)
+	return uint16(memory.Peek(cpu.Memory, cpu.PC)) | uint16(memory.Peek(cpu.Memory, cpu.PC+1))<<8
 }
 
+// Push pushes a byte on the stack, taking one cycle
 func (cpu *CPU) Push(v byte) {
 	//cpu.msg("CPU Stack push $%02x", v)
-	cpu.Memory.MemWrite(0x100+uint16(cpu.S), v)
+	cpu.write(0x100+uint16(cpu.S), v)
 	cpu.S -= 1
 }
 
+// Pull pulls a byte from the stack, taking one cycle
 func (cpu *CPU) Pull() byte {
 	cpu.S += 1
-	v := cpu.Memory.MemRead(0x100 + uint16(cpu.S))
+	v := cpu.read(0x100 + uint16(cpu.S))
 	//cpu.msg("CPU Stack pull $%02x S=%02x", v, cpu.S)
 	return v
 }
 
+// Push16 pushes a word on the stack, high byte first, taking two cycles
 func (cpu *CPU) Push16(v uint16) {
-	// TODO is this the right order?
 	cpu.Push(uint8((v >> 8) & 0xff))
 	cpu.Push(uint8(v & 0xff))
 }
 
+// Pull16 pulls a word from the stack, taking two cycles
 func (cpu *CPU) Pull16() uint16 {
 	var v uint16
 	v = uint16(cpu.Pull())
@@ -157,6 +257,13 @@ func (cpu *CPU) Pull16() uint16 {
 	return v
 }
 
+// dummyPull is the stack read done before pulling, while the stack pointer
+// is incremented
+func (cpu *CPU) dummyPull() {
+	cpu.read(0x100 + uint16(cpu.S))
+}
+
+// Read16 reads a word from memory without consuming cycles
 func (cpu *CPU) Read16(offt uint16) uint16 {
 	// little endian read
 	a := cpu.Memory.MemRead(offt)
@@ -165,20 +272,21 @@ func (cpu *CPU) Read16(offt uint16) uint16 {
 	return uint16(a) | uint16(b)<<8
 }
 
+// Read16W reads a word wrapping around the current page, taking two cycles
 func (cpu *CPU) Read16W(offt uint16) uint16 {
 	// little endian read wrapping around current page
-	a := cpu.Memory.MemRead(offt)
+	a := cpu.read(offt)
 	if offt&0xff == 0xff {
 		// would wrap
 		offt &= 0xff00
 	} else {
 		offt += 1
 	}
-	b := cpu.Memory.MemRead(offt)
+	b := cpu.read(offt)
 
 	return uint16(a) | uint16(b)<<8
 }
 
 func (cpu *CPU) String() string {
-	return fmt.Sprintf("CPU:6502 [A=%02x X=%02x Y=%02x PC=%04x S=%02x P=%02x]", cpu.A, cpu.X, cpu.Y, cpu.PC, cpu.S, cpu.P)
+	return fmt.Sprintf("CPU:%s [A=%02x X=%02x Y=%02x PC=%04x S=%02x P=%02x]", cpu.variant, cpu.A, cpu.X, cpu.Y, cpu.PC, cpu.S, cpu.P)
 }
@@ -24,7 +24,18 @@ func cpy(cpu *CPU, am AddressMode) {
 func adc(cpu *CPU, am AddressMode) {
 	// Add Memory to Accumulator with Carry
 	// A + M + C -> A, C
-	v := am.Read(cpu)
+	cpu.add(am.Read(cpu))
+}
+
+func sbc(cpu *CPU, am AddressMode) {
+	// Subtract Memory from Accumulator with Borrow
+	// A - M - C -> A
+	cpu.sub(am.Read(cpu))
+}
+
+// add adds v and the carry to A, in decimal mode if the D flag is set and
+// the variant supports it
+func (cpu *CPU) add(v byte) {
 	c := byte(0)
 	if cpu.getFlag(FlagCarry) {
 		c = 1
@@ -37,12 +48,40 @@ func adc(cpu *CPU, am AddressMode) {
 
 	cpu.setFlag(FlagCarry, int(a)+int(v)+int(c) > 0xff)
 	cpu.setFlag(FlagOverflow, (a^v)&0x80 == 0 && (a^cpu.A)&0x80 != 0)
+
+	if cpu.P&FlagDecimal == 0 || !cpu.variant.decimal() {
+		return
+	}
+
+	// See: http://www.6502.org/tutorials/decimal_mode.html#A
+	lo := int(a&0x0f) + int(v&0x0f) + int(c)
+	if lo >= 0x0a {
+		lo = ((lo + 0x06) & 0x0f) + 0x10
+	}
+	res := int(a&0xf0) + int(v&0xf0) + lo
+
+	// V is set from the result before the high digit is fixed, and on the
+	// NMOS 6502 N too, while Z is set from the binary result
+	signed := int(int8(a&0xf0)) + int(int8(v&0xf0)) + lo
+	cpu.setFlag(FlagOverflow, signed < -128 || signed > 127)
+	cpu.flagsN(byte(res))
+
+	if res >= 0xa0 {
+		res += 0x60
+	}
+	cpu.A = byte(res)
+	cpu.setFlag(FlagCarry, res >= 0x100)
+
+	if cpu.variant == Variant65C02 {
+		// the 65C02 sets N and Z from the result, which takes one more cycle
+		cpu.read(cpu.PC)
+		cpu.flagsNZ(cpu.A)
+	}
 }
 
-func sbc(cpu *CPU, am AddressMode) {
-	// Subtract Memory from Accumulator with Borrow
-	// A - M - C -> A
-	v := am.Read(cpu)
+// sub subtracts v and the borrow from A, in decimal mode if the D flag is set
+// and the variant supports it
+func (cpu *CPU) sub(v byte) {
 	c := byte(0)
 	if cpu.getFlag(FlagCarry) {
 		c = 1
@@ -55,21 +94,66 @@ func sbc(cpu *CPU, am AddressMode) {
 
 	cpu.setFlag(FlagCarry, int(a)-int(v)-int(1-c) >= 0)
 	cpu.setFlag(FlagOverflow, (a^v)&0x80 != 0 && (a^cpu.A)&0x80 != 0)
+
+	if cpu.P&FlagDecimal == 0 || !cpu.variant.decimal() {
+		return
+	}
+
+	// See: http://www.6502.org/tutorials/decimal_mode.html#A
+	// C and V are the same as in binary mode
+	lo := int(a&0x0f) - int(v&0x0f) + int(c) - 1
+	if cpu.variant == Variant65C02 {
+		res := int(a) - int(v) + int(c) - 1
+		if res < 0 {
+			res -= 0x60
+		}
+		if lo < 0 {
+			res -= 0x06
+		}
+		cpu.A = byte(res)
+
+		// N and Z are set from the result, which takes one more cycle
+		cpu.read(cpu.PC)
+		cpu.flagsNZ(cpu.A)
+		return
+	}
+
+	// the NMOS 6502 sets N and Z from the binary result
+	if lo < 0 {
+		lo = ((lo - 0x06) & 0x0f) - 0x10
+	}
+	res := int(a&0xf0) - int(v&0xf0) + lo
+	if res < 0 {
+		res -= 0x60
+	}
+	cpu.A = byte(res)
 }
 
 func inc(cpu *CPU, am AddressMode) {
+	if am == amAcc {
+		// 65C02 only
+		cpu.A += 1
+		cpu.flagsNZ(cpu.A)
+		return
+	}
 	addr := am.AddrFast(cpu)
-	v := cpu.Memory.MemRead(addr)
+	v := cpu.readModify(addr)
 	v += 1
-	cpu.Memory.MemWrite(addr, v)
+	cpu.write(addr, v)
 	cpu.flagsNZ(v)
 }
 
 func dec(cpu *CPU, am AddressMode) {
+	if am == amAcc {
+		// 65C02 only
+		cpu.A -= 1
+		cpu.flagsNZ(cpu.A)
+		return
+	}
 	addr := am.AddrFast(cpu)
-	v := cpu.Memory.MemRead(addr)
+	v := cpu.readModify(addr)
 	v -= 1
-	cpu.Memory.MemWrite(addr, v)
+	cpu.write(addr, v)
 	cpu.flagsNZ(v)
 }
 
@@ -77,9 +161,9 @@ func dcp(cpu *CPU, am AddressMode) {
 	// M - 1 -> M, A - M
 	// Flags: N Z C
 	addr := am.AddrFast(cpu)
-	v := cpu.Memory.MemRead(addr)
+	v := cpu.readModify(addr)
 	v -= 1
-	cpu.Memory.MemWrite(addr, v)
+	cpu.write(addr, v)
 
 	cpu.flagsNZ(cpu.A - v)
 	cpu.setFlag(FlagCarry, int(cpu.A)-int(v) >= 0)
@@ -91,23 +175,10 @@ func isc(cpu *CPU, am AddressMode) {
 	// Flags: N Z C V
 
 	addr := am.AddrFast(cpu)
-	v := cpu.Memory.MemRead(addr)
+	v := cpu.readModify(addr)
 
 	v += 1
-	cpu.Memory.MemWrite(addr, v)
-
-	// code after that is same as sbc
-
-	c := byte(0)
-	if cpu.getFlag(FlagCarry) {
-		c = 1
-	}
-
-	a := cpu.A
-	cpu.A = a - v - (1 - c)
-
-	cpu.flagsNZ(cpu.A)
+	cpu.write(addr, v)
 
-	cpu.setFlag(FlagCarry, int(a)-int(v)-int(1-c) >= 0)
-	cpu.setFlag(FlagOverflow, (a^v)&0x80 != 0 && (a^cpu.A)&0x80 != 0)
+	cpu.sub(v)
 }
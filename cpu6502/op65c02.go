@@ -0,0 +1,216 @@
+package cpu6502
+
+import "fmt"
+
+// cpu65c02op is the opcode table of the 65C02, made of the NMOS opcodes with
+// the undocumented opcodes replaced by new instructions or NOPs
+// see: http://www.6502.org/tutorials/65c02opcodes.html
+var cpu65c02op [256]*op
+
+func init() {
+	cpu65c02op = cpu6502op
+
+	for n := 0; n < 256; n++ {
+		bit := byte(1) << (n >> 4 & 7)
+		switch {
+		case n&0x07 == 0x03:
+			// $x3 and $xB are single byte, single cycle NOPs
+			cpu65c02op[n] = &op{"NOP", nop, amImpl, 1}
+		case n&0x0f == 0x07 && n < 0x80:
+			cpu65c02op[n] = &op{fmt.Sprintf("RMB%d", n>>4), rmb(bit), amZpg, 5}
+		case n&0x0f == 0x07:
+			cpu65c02op[n] = &op{fmt.Sprintf("SMB%d", n>>4&7), smb(bit), amZpg, 5}
+		case n&0x0f == 0x0f && n < 0x80:
+			cpu65c02op[n] = &op{fmt.Sprintf("BBR%d", n>>4), bbr(bit), amZpgRel, 5}
+		case n&0x0f == 0x0f:
+			cpu65c02op[n] = &op{fmt.Sprintf("BBS%d", n>>4&7), bbs(bit), amZpgRel, 5}
+		}
+	}
+
+	for n, o := range map[byte]*op{
+		// 0x00
+		0x02: {"NOP", nop, amImmed, 2},
+		0x04: {"TSB", tsb, amZpg, 5},
+		0x0c: {"TSB", tsb, amAbs, 6},
+
+		// 0x10
+		0x12: {"ORA", ora, amZpgInd, 5},
+		0x14: {"TRB", trb, amZpg, 5},
+		0x1a: {"INC", inc, amAcc, 2},
+		0x1c: {"TRB", trb, amAbs, 6},
+
+		// 0x20
+		0x22: {"NOP", nop, amImmed, 2},
+
+		// 0x30
+		0x32: {"AND", and, amZpgInd, 5},
+		0x34: {"BIT", bit, amZpgX, 4},
+		0x3a: {"DEC", dec, amAcc, 2},
+		0x3c: {"BIT", bit, amAbsX, 4},
+
+		// 0x40
+		0x42: {"NOP", nop, amImmed, 2},
+		0x44: {"NOP", nop, amZpg, 3},
+
+		// 0x50
+		0x52: {"EOR", eor, amZpgInd, 5},
+		0x54: {"NOP", nop, amZpgX, 4},
+		0x5a: {"PHY", phy, amImpl, 3},
+		0x5c: {"NOP", nop5c, amAbs, 8},
+
+		// 0x60
+		0x62: {"NOP", nop, amImmed, 2},
+		0x64: {"STZ", stz, amZpg, 3},
+		0x6c: {"JMP", jmp, amInd, 6},
+
+		// 0x70
+		0x72: {"ADC", adc, amZpgInd, 5},
+		0x74: {"STZ", stz, amZpgX, 4},
+		0x7a: {"PLY", ply, amImpl, 4},
+		0x7c: {"JMP", jmp, amAbsIndX, 6},
+
+		// 0x80
+		0x80: {"BRA", bra, amRel, 3},
+		0x82: {"NOP", nop, amImmed, 2},
+		0x89: {"BIT", bitImmed, amImmed, 2},
+
+		// 0x90
+		0x92: {"STA", sta, amZpgInd, 5},
+		0x9c: {"STZ", stz, amAbs, 4},
+		0x9e: {"STZ", stz, amAbsX, 5},
+
+		// 0xb0
+		0xb2: {"LDA", lda, amZpgInd, 5},
+
+		// 0xc0
+		0xc2: {"NOP", nop, amImmed, 2},
+		0xcb: {"WAI", wai, amImpl, 3},
+
+		// 0xd0
+		0xd2: {"CMP", cmp, amZpgInd, 5},
+		0xd4: {"NOP", nop, amZpgX, 4},
+		0xda: {"PHX", phx, amImpl, 3},
+		0xdb: {"STP", stop, amImpl, 3},
+		0xdc: {"NOP", nop, amAbs, 4},
+
+		// 0xe0
+		0xe2: {"NOP", nop, amImmed, 2},
+
+		// 0xf0
+		0xf2: {"SBC", sbc, amZpgInd, 5},
+		0xf4: {"NOP", nop, amZpgX, 4},
+		0xfa: {"PLX", plx, amImpl, 4},
+		0xfc: {"NOP", nop, amAbs, 4},
+	} {
+		cpu65c02op[n] = o
+	}
+}
+
+func bra(cpu *CPU, am AddressMode) {
+	cpu.branchTo(am.Addr(cpu))
+}
+
+func stz(cpu *CPU, am AddressMode) {
+	am.Write(cpu, 0)
+}
+
+func bitImmed(cpu *CPU, am AddressMode) {
+	// unlike other BIT instructions, only Z is affected
+	cpu.flagsZ(am.Read(cpu) & cpu.A)
+}
+
+func tsb(cpu *CPU, am AddressMode) {
+	// Test and Set Bits: A AND M -> Z, A OR M -> M
+	addr := am.Addr(cpu)
+	v := cpu.readModify(addr)
+	cpu.flagsZ(v & cpu.A)
+	cpu.write(addr, v|cpu.A)
+}
+
+func trb(cpu *CPU, am AddressMode) {
+	// Test and Reset Bits: A AND M -> Z, NOT A AND M -> M
+	addr := am.Addr(cpu)
+	v := cpu.readModify(addr)
+	cpu.flagsZ(v & cpu.A)
+	cpu.write(addr, v&^cpu.A)
+}
+
+func phx(cpu *CPU, am AddressMode) {
+	am.Implied(cpu)
+	cpu.Push(cpu.X)
+}
+
+func phy(cpu *CPU, am AddressMode) {
+	am.Implied(cpu)
+	cpu.Push(cpu.Y)
+}
+
+func plx(cpu *CPU, am AddressMode) {
+	am.Implied(cpu)
+	cpu.dummyPull()
+	cpu.X = cpu.Pull()
+	cpu.flagsNZ(cpu.X)
+}
+
+func ply(cpu *CPU, am AddressMode) {
+	am.Implied(cpu)
+	cpu.dummyPull()
+	cpu.Y = cpu.Pull()
+	cpu.flagsNZ(cpu.Y)
+}
+
+// rmb returns the RMBx instruction, which clears the given bit in memory
+func rmb(bit byte) func(cpu *CPU, am AddressMode) {
+	return func(cpu *CPU, am AddressMode) {
+		addr := am.Addr(cpu)
+		cpu.write(addr, cpu.readModify(addr)&^bit)
+	}
+}
+
+// smb returns the SMBx instruction, which sets the given bit in memory
+func smb(bit byte) func(cpu *CPU, am AddressMode) {
+	return func(cpu *CPU, am AddressMode) {
+		addr := am.Addr(cpu)
+		cpu.write(addr, cpu.readModify(addr)|bit)
+	}
+}
+
+// bbr returns the BBRx instruction, which branches if the given bit is clear
+func bbr(bit byte) func(cpu *CPU, am AddressMode) {
+	return func(cpu *CPU, am AddressMode) {
+		cpu.branchBit(bit, false)
+	}
+}
+
+// bbs returns the BBSx instruction, which branches if the given bit is set
+func bbs(bit byte) func(cpu *CPU, am AddressMode) {
+	return func(cpu *CPU, am AddressMode) {
+		cpu.branchBit(bit, true)
+	}
+}
+
+// branchBit branches if the given bit of a zeropage byte is set (or clear)
+func (cpu *CPU) branchBit(bit byte, set bool) {
+	addr := uint16(cpu.ReadPC())
+	v := cpu.read(addr)
+	cpu.read(addr) // dummy read while the bit is tested
+	target := amRel.Addr(cpu)
+	if (v&bit != 0) == set {
+		cpu.branchTo(target)
+	}
+}
+
+func wai(cpu *CPU, am AddressMode) {
+	// wait for an interrupt, see Clock
+	cpu.read(cpu.PC)
+	cpu.wait = true
+}
+
+// nop5c is the 65C02's $5C NOP, which reads its operand and then spends 5
+// more cycles reading
+func nop5c(cpu *CPU, am AddressMode) {
+	addr := am.Addr(cpu)
+	for n := 0; n < 5; n++ {
+		cpu.read(0xff00 | addr&0xff)
+	}
+}
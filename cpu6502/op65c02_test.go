@@ -0,0 +1,157 @@
+package cpu6502
+
+import (
+	"testing"
+
+	"github.com/MagicalTux/gones/memory"
+)
+
+// testCPU returns a CPU of the given variant with 32KB of RAM, and prog
+// loaded at $0200 where PC points
+func testCPU(v Variant, prog ...byte) (*CPU, memory.RAM) {
+	bus := memory.NewBus().(*memory.Bus)
+	ram := memory.NewRAM(0x8000)
+	bus.MapHandler(0x0000, 0x8000, ram)
+
+	cpu := New(v)
+	cpu.Memory = bus
+	cpu.S = 0xfd
+	cpu.P = FlagIgnored | FlagInterruptDisable
+	cpu.PC = 0x200
+	copy(ram[0x200:], prog)
+	return cpu, ram
+}
+
+func TestBranchBit(t *testing.T) {
+	tests := []struct {
+		name string
+		prog []byte
+		zpg  byte
+		pc   uint16
+		cyc  uint64
+	}{
+		{"BBR0 clear", []byte{0x0f, 0x10, 0x04}, 0xfe, 0x207, 6},
+		{"BBR0 set", []byte{0x0f, 0x10, 0x04}, 0x01, 0x203, 5},
+		{"BBS7 set", []byte{0xff, 0x10, 0x04}, 0x80, 0x207, 6},
+		{"BBS7 clear", []byte{0xff, 0x10, 0x04}, 0x7f, 0x203, 5},
+		{"BBS3 backward", []byte{0xbf, 0x10, 0xfb}, 0x08, 0x1fe, 7},
+		{"BBR5 to itself", []byte{0x5f, 0x10, 0xfd}, 0x00, 0x200, 6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cpu, ram := testCPU(Variant65C02, tt.prog...)
+			ram[0x10] = tt.zpg
+
+			if cyc := cpu.Clock(0); cyc != tt.cyc {
+				t.Errorf("took %d cycles, want %d", cyc, tt.cyc)
+			}
+			if cpu.PC != tt.pc {
+				t.Errorf("PC = $%04x, want $%04x", cpu.PC, tt.pc)
+			}
+			if ram[0x10] != tt.zpg {
+				t.Errorf("tested byte changed to $%02x", ram[0x10])
+			}
+		})
+	}
+}
+
+func TestTSBTRB(t *testing.T) {
+	tests := []struct {
+		name string
+		op   byte
+		a    byte
+		m    byte
+		want byte
+		zero bool
+	}{
+		{"TSB disjoint", 0x04, 0x0f, 0xf0, 0xff, true},
+		{"TSB overlap", 0x04, 0x01, 0x03, 0x03, false},
+		{"TSB zero", 0x04, 0x00, 0x00, 0x00, true},
+		{"TRB overlap", 0x14, 0x01, 0x03, 0x02, false},
+		{"TRB disjoint", 0x14, 0x04, 0x03, 0x03, true},
+		{"TRB all", 0x14, 0xff, 0x81, 0x00, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cpu, ram := testCPU(Variant65C02, tt.op, 0x10)
+			ram[0x10] = tt.m
+			cpu.A = tt.a
+			// N, V and C are left alone
+			cpu.P |= FlagNegative | FlagOverflow | FlagCarry
+
+			if cyc := cpu.Clock(0); cyc != 5 {
+				t.Errorf("took %d cycles, want 5", cyc)
+			}
+			if ram[0x10] != tt.want {
+				t.Errorf("M = $%02x, want $%02x", ram[0x10], tt.want)
+			}
+			if cpu.A != tt.a {
+				t.Errorf("A changed to $%02x", cpu.A)
+			}
+			if z := cpu.P&FlagZero != 0; z != tt.zero {
+				t.Errorf("Z = %v, want %v", z, tt.zero)
+			}
+			if keep := FlagNegative | FlagOverflow | FlagCarry; cpu.P&keep != keep {
+				t.Errorf("P = $%02x, N, V or C cleared", cpu.P)
+			}
+		})
+	}
+}
+
+func TestDecimal(t *testing.T) {
+	tests := []struct {
+		name    string
+		variant Variant
+		op      byte // ADC or SBC immediate
+		a       byte
+		v       byte
+		carry   bool
+		want    byte
+		wantC   bool
+		wantZ   bool
+		wantN   bool
+		cyc     uint64
+	}{
+		{"ADC", Variant65C02, 0x69, 0x12, 0x34, false, 0x46, false, false, false, 3},
+		{"ADC carry in", Variant65C02, 0x69, 0x58, 0x46, true, 0x05, true, false, false, 3},
+		{"ADC carry out", Variant65C02, 0x69, 0x81, 0x92, false, 0x73, true, false, false, 3},
+		// the NMOS 6502 sets Z from the binary result $9a
+		{"ADC zero", Variant65C02, 0x69, 0x99, 0x01, false, 0x00, true, true, false, 3},
+		{"ADC zero NMOS", Variant6502, 0x69, 0x99, 0x01, false, 0x00, true, false, true, 2},
+		{"ADC negative", Variant65C02, 0x69, 0x79, 0x01, false, 0x80, false, false, true, 3},
+		{"SBC", Variant65C02, 0xe9, 0x46, 0x12, true, 0x34, true, false, false, 3},
+		{"SBC borrow in", Variant65C02, 0xe9, 0x40, 0x13, false, 0x26, true, false, false, 3},
+		{"SBC borrow out", Variant65C02, 0xe9, 0x12, 0x21, true, 0x91, false, false, true, 3},
+		{"SBC zero", Variant65C02, 0xe9, 0x21, 0x21, true, 0x00, true, true, false, 3},
+		{"SBC wrap", Variant65C02, 0xe9, 0x00, 0x01, true, 0x99, false, false, true, 3},
+		// the 2A03 has no decimal mode
+		{"ADC 2A03", Variant2A03, 0x69, 0x09, 0x01, false, 0x0a, false, false, false, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cpu, _ := testCPU(tt.variant, tt.op, tt.v)
+			cpu.A = tt.a
+			cpu.P |= FlagDecimal
+			cpu.setFlag(FlagCarry, tt.carry)
+
+			if cyc := cpu.Clock(0); cyc != tt.cyc {
+				t.Errorf("took %d cycles, want %d", cyc, tt.cyc)
+			}
+			if cpu.A != tt.want {
+				t.Errorf("A = $%02x, want $%02x", cpu.A, tt.want)
+			}
+			if c := cpu.P&FlagCarry != 0; c != tt.wantC {
+				t.Errorf("C = %v, want %v", c, tt.wantC)
+			}
+			if z := cpu.P&FlagZero != 0; z != tt.wantZ {
+				t.Errorf("Z = %v, want %v", z, tt.wantZ)
+			}
+			if n := cpu.P&FlagNegative != 0; n != tt.wantN {
+				t.Errorf("N = %v, want %v", n, tt.wantN)
+			}
+		})
+	}
+}
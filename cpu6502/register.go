@@ -16,15 +16,15 @@ func ldy(cpu *CPU, am AddressMode) {
 }
 
 func sta(cpu *CPU, am AddressMode) {
-	am.WriteFast(cpu, cpu.A)
+	am.Write(cpu, cpu.A)
 }
 
 func stx(cpu *CPU, am AddressMode) {
-	am.WriteFast(cpu, cpu.X)
+	am.Write(cpu, cpu.X)
 }
 
 func sty(cpu *CPU, am AddressMode) {
-	am.WriteFast(cpu, cpu.Y)
+	am.Write(cpu, cpu.Y)
 }
 
 func tax(cpu *CPU, am AddressMode) {
@@ -31,6 +31,7 @@ func pha(cpu *CPU, am AddressMode) {
 
 func pla(cpu *CPU, am AddressMode) {
 	am.Implied(cpu)
+	cpu.dummyPull()
 	cpu.A = cpu.Pull()
 	cpu.flagsNZ(cpu.A)
 }
@@ -42,5 +43,6 @@ func php(cpu *CPU, am AddressMode) {
 
 func plp(cpu *CPU, am AddressMode) {
 	am.Implied(cpu)
+	cpu.dummyPull()
 	cpu.P = cpu.Pull() & ^FlagBreak | FlagIgnored
 }
@@ -0,0 +1,74 @@
+package cpu6502
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// cpuState is the serialized form of the CPU, see SaveState
+type cpuState struct {
+	A, X, Y byte
+	PC      uint16
+	S, P    byte
+	Fault   bool
+	Wait    bool
+	NMI     bool
+	IRQ     IRQSource
+	Pending bool
+	Cyc     uint64
+
+	// pending DMA, DMA doesn't run across instructions
+	OAMDMA  bool
+	OAMPage byte
+	DMCDMA  bool
+	DMCAddr uint16
+}
+
+// SaveState writes the state of the CPU to w
+func (cpu *CPU) SaveState(w io.Writer) error {
+	st := &cpuState{
+		A:       cpu.A,
+		X:       cpu.X,
+		Y:       cpu.Y,
+		PC:      cpu.PC,
+		S:       cpu.S,
+		P:       cpu.P,
+		Fault:   cpu.fault,
+		Wait:    cpu.wait,
+		NMI:     cpu.nmi,
+		IRQ:     cpu.irq,
+		Pending: cpu.pending,
+		Cyc:     cpu.cyc,
+		OAMDMA:  cpu.dma.oam,
+		OAMPage: cpu.dma.oamPage,
+		DMCDMA:  cpu.dma.dmc,
+		DMCAddr: cpu.dma.dmcAddr,
+	}
+	return binary.Write(w, binary.LittleEndian, st)
+}
+
+// LoadState restores a state written by SaveState
+func (cpu *CPU) LoadState(r io.Reader) error {
+	var st cpuState
+	if err := binary.Read(r, binary.LittleEndian, &st); err != nil {
+		return err
+	}
+
+	cpu.A, cpu.X, cpu.Y = st.A, st.X, st.Y
+	cpu.PC = st.PC
+	cpu.S, cpu.P = st.S, st.P
+	cpu.fault = st.Fault
+	cpu.wait = st.Wait
+	cpu.nmi = st.NMI
+	cpu.irq = st.IRQ
+	cpu.pending = st.Pending
+	cpu.cyc = st.Cyc
+	cpu.dma = dma{}
+	if st.OAMDMA {
+		cpu.StartOAMDMA(st.OAMPage)
+	}
+	if st.DMCDMA {
+		cpu.StartDMCDMA(st.DMCAddr)
+	}
+	return nil
+}
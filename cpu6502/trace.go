@@ -0,0 +1,137 @@
+package cpu6502
+
+import (
+	"fmt"
+
+	"github.com/MagicalTux/gones/memory"
+)
+
+// TraceFormat selects the format of the lines written to CPU.Trace
+type TraceFormat int
+
+const (
+	// TraceDefault is gones' own format, as found in doc/trace_nestest.log:
+	// CPU Step cyc=7: [$c000] JMP abs = $c5f5   CPU:2A03 [A=00 X=00 Y=00 PC=c001 S=fd P=24]
+	TraceDefault TraceFormat = iota
+
+	// TraceNestest is the format of Nintendulator, used by nestest.log and
+	// supported by most emulators:
+	// C000  4C F5 C5  JMP $C5F5                       A:00 X:00 Y:00 P:24 SP:FD PPU:  0, 21 CYC:7
+	// The PPU position is only included if CPU.TracePPU is set.
+	TraceNestest
+)
+
+// mnemonics that are spelled differently in nestest.log
+var nestestMnemonics = map[string]string{
+	"ISC":  "ISB",
+	"USBC": "SBC",
+}
+
+// trace writes the trace line for the instruction at pos, which started at
+// cycle cyc, before it is run
+func (cpu *CPU) trace(pos uint16, cyc uint64, o *op) {
+	switch cpu.TraceFormat {
+	case TraceNestest:
+		cpu.traceNestest(pos, cyc)
+	default:
+		fmt.Fprintf(cpu.Trace, "CPU Step cyc=%d: [$%04x] %s % -32s %s\n", cyc, pos, o.i, o.am.Debug(cpu), cpu)
+	}
+}
+
+func (cpu *CPU) traceNestest(pos uint16, cyc uint64) {
+	var code [3]byte
+	for n := range code {
+		code[n] = cpu.peek(pos + uint16(n))
+	}
+	i := cpu.variant.DisassembleOne(code[:], pos)
+
+	mnemonic := i.Mnemonic
+	if m, ok := nestestMnemonics[mnemonic]; ok {
+		mnemonic = m
+	}
+	prefix := " "
+	if i.Illegal {
+		prefix = "*"
+	}
+	if op := cpu.nestestOperand(i); op != "" {
+		mnemonic += " " + op
+	}
+
+	var ppu string
+	if cpu.TracePPU != nil {
+		scanline, dot := cpu.TracePPU()
+		ppu = fmt.Sprintf(" PPU:%3d,%3d", scanline, dot)
+	}
+
+	fmt.Fprintf(cpu.Trace, "%04X  %-8s %s%-32sA:%02X X:%02X Y:%02X P:%02X SP:%02X%s CYC:%d\n", pos, i.Hex(), prefix, mnemonic, cpu.A, cpu.X, cpu.Y, cpu.P, cpu.S, ppu, cyc)
+}
+
+// nestestOperand returns the operand of i with its effective address and
+// the value found there, the way Nintendulator does
+func (cpu *CPU) nestestOperand(i *Instruction) string {
+	op := i.Operand
+
+	switch i.Mode {
+	case amAcc:
+		return "A"
+	case amImmed:
+		return fmt.Sprintf("#$%02X", op)
+	case amRel:
+		return fmt.Sprintf("$%04X", op)
+	case amZpg:
+		return fmt.Sprintf("$%02X = %02X", op, cpu.peek(op))
+	case amZpgX:
+		addr := uint16(byte(op) + cpu.X)
+		return fmt.Sprintf("$%02X,X @ %02X = %02X", op, addr, cpu.peek(addr))
+	case amZpgY:
+		addr := uint16(byte(op) + cpu.Y)
+		return fmt.Sprintf("$%02X,Y @ %02X = %02X", op, addr, cpu.peek(addr))
+	case amAbs:
+		if i.Mnemonic == "JMP" || i.Mnemonic == "JSR" {
+			return fmt.Sprintf("$%04X", op)
+		}
+		return fmt.Sprintf("$%04X = %02X", op, cpu.peek(op))
+	case amAbsX:
+		addr := op + uint16(cpu.X)
+		return fmt.Sprintf("$%04X,X @ %04X = %02X", op, addr, cpu.peek(addr))
+	case amAbsY:
+		addr := op + uint16(cpu.Y)
+		return fmt.Sprintf("$%04X,Y @ %04X = %02X", op, addr, cpu.peek(addr))
+	case amInd:
+		return fmt.Sprintf("($%04X) = %04X", op, cpu.peek16W(op))
+	case amIndX:
+		ptr := uint16(byte(op) + cpu.X)
+		addr := cpu.peek16W(ptr)
+		return fmt.Sprintf("($%02X,X) @ %02X = %04X = %02X", op, ptr, addr, cpu.peek(addr))
+	case amIndY:
+		base := cpu.peek16W(op)
+		addr := base + uint16(cpu.Y)
+		return fmt.Sprintf("($%02X),Y = %04X @ %04X = %02X", op, base, addr, cpu.peek(addr))
+	case amZpgInd:
+		addr := cpu.peek16W(op)
+		return fmt.Sprintf("($%02X) = %04X = %02X", op, addr, cpu.peek(addr))
+	case amAbsIndX:
+		ptr := op + uint16(cpu.X)
+		return fmt.Sprintf("($%04X,X) @ %04X = %04X", op, ptr, uint16(cpu.peek(ptr))|uint16(cpu.peek(ptr+1))<<8)
+	case amZpgRel:
+		return fmt.Sprintf("$%02X = %02X,$%04X", i.Bytes[1], cpu.peek(uint16(i.Bytes[1])), op)
+	default:
+		return ""
+	}
+}
+
+// peek reads memory for tracing purposes, without side effects (see
+// memory.Peek). I/O registers ($2000-$401F) return $FF like in Nintendulator
+// traces.
+func (cpu *CPU) peek(addr uint16) byte {
+	if addr >= 0x2000 && addr < 0x4020 {
+		return 0xff
+	}
+	return memory.Peek(cpu.Memory, addr)
+}
+
+// peek16W is the peek version of Read16W
+func (cpu *CPU) peek16W(addr uint16) uint16 {
+	next := addr&0xff00 | uint16(byte(addr)+1)
+	return uint16(cpu.peek(addr)) | uint16(cpu.peek(next))<<8
+}
@@ -0,0 +1,61 @@
+package cpu6502
+
+import (
+	"testing"
+
+	"github.com/MagicalTux/gones/memory"
+)
+
+func TestParseTraceLine(t *testing.T) {
+	want := TraceLine{Cyc: 7, Addr: 0xc000, PC: 0xc001, S: 0xfd, P: 0x24}
+
+	tests := []struct {
+		name string
+		line string
+		want TraceLine
+	}{
+		{"default", "CPU Step cyc=7: [$c000] JMP abs = $c5f5                      CPU:2A03 [A=00 X=00 Y=00 PC=c001 S=fd P=24]", want},
+		{"nestest", "C000  4C F5 C5  JMP $C5F5                       A:00 X:00 Y:00 P:24 SP:FD PPU:  0, 21 CYC:7",
+			TraceLine{Cyc: 7, Addr: 0xc000, PC: 0xc001, S: 0xfd, P: 0x24, PPU: true, Dot: 21}},
+		{"nestest without PPU", "C000  4C F5 C5  JMP $C5F5                       A:00 X:00 Y:00 P:24 SP:FD CYC:7", want},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTraceLine(tt.line)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := ParseTraceLine("garbage"); err == nil {
+		t.Errorf("parsed an invalid line")
+	}
+}
+
+func TestTracePeek(t *testing.T) {
+	bus := memory.NewBus().(*memory.Bus)
+	ram := memory.NewRAM(0x800)
+	bus.MapHandler(0x0000, 0x2000, ram)
+	cpu := New(Variant2A03)
+	cpu.Memory = bus
+
+	copy(ram[0x200:], []byte{0xad, 0x34, 0x12}) // LDA $1234
+	ram[0x234] = 0x56
+	cpu.PC = 0x200
+	bus.MemWrite(0x5000, 0x99)
+
+	if v := cpu.PeekPC16(); v != 0x34ad {
+		t.Errorf("PeekPC16 = $%04x, want $34ad", v)
+	}
+	if s := cpu.nestestOperand(cpu.variant.DisassembleOne(ram[0x200:0x203], 0x200)); s != "$1234 = 56" {
+		t.Errorf("operand %q, want \"$1234 = 56\"", s)
+	}
+	if v := bus.OpenBus(); v != 0x99 {
+		t.Errorf("tracing changed the open bus to $%02x", v)
+	}
+}
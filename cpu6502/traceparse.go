@@ -0,0 +1,98 @@
+package cpu6502
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// traceRe matches lines written by Trace in the default format (TraceDefault), such as:
+// CPU Step cyc=7: [$c000] JMP abs = $c5f5   CPU:2A03 [A=00 X=00 Y=00 PC=c001 S=fd P=24]
+var traceRe = regexp.MustCompile(`^CPU Step cyc=(\d+): \[\$([0-9a-f]{4})\].*\[A=([0-9a-f]{2}) X=([0-9a-f]{2}) Y=([0-9a-f]{2}) PC=([0-9a-f]{4}) S=([0-9a-f]{2}) P=([0-9a-f]{2})\]\s*$`)
+
+// nestestRe matches lines in the Nintendulator/nestest.log format, such as:
+// C000  4C F5 C5  JMP $C5F5                       A:00 X:00 Y:00 P:24 SP:FD PPU:  0, 21 CYC:7
+var nestestRe = regexp.MustCompile(`^([0-9A-F]{4}) .*A:([0-9A-F]{2}) X:([0-9A-F]{2}) Y:([0-9A-F]{2}) P:([0-9A-F]{2}) SP:([0-9A-F]{2})(?: PPU: *(-?\d+), *(\d+))? CYC:(\d+)\s*$`)
+
+// TraceLine is the CPU state found in a trace line. The CPU variant name is
+// ignored so traces from any variant can be compared.
+type TraceLine struct {
+	Cyc  uint64 // CPU cycle
+	Addr uint16 // address of the instruction
+	A    byte
+	X    byte
+	Y    byte
+	PC   uint16 // PC after reading the opcode, always Addr+1
+	S    byte
+	P    byte
+
+	PPU      bool // true if the line has the PPU position
+	Scanline int
+	Dot      int
+}
+
+// ParseTraceLine parses a line written by Trace, in either the default or
+// the nestest format
+func ParseTraceLine(s string) (TraceLine, error) {
+	var l TraceLine
+
+	hex := func(s string) uint64 {
+		v, _ := strconv.ParseUint(s, 16, 16)
+		return v
+	}
+
+	if m := nestestRe.FindStringSubmatch(s); m != nil {
+		l.Addr = uint16(hex(m[1]))
+		l.PC = l.Addr + 1
+		l.A = byte(hex(m[2]))
+		l.X = byte(hex(m[3]))
+		l.Y = byte(hex(m[4]))
+		l.P = byte(hex(m[5]))
+		l.S = byte(hex(m[6]))
+		if m[7] != "" {
+			l.PPU = true
+			l.Scanline, _ = strconv.Atoi(m[7])
+			l.Dot, _ = strconv.Atoi(m[8])
+		}
+		l.Cyc, _ = strconv.ParseUint(m[9], 10, 64)
+		return l, nil
+	}
+
+	m := traceRe.FindStringSubmatch(s)
+	if m == nil {
+		return l, fmt.Errorf("invalid trace line: %q", s)
+	}
+
+	l.Cyc, _ = strconv.ParseUint(m[1], 10, 64)
+	l.Addr = uint16(hex(m[2]))
+	l.A = byte(hex(m[3]))
+	l.X = byte(hex(m[4]))
+	l.Y = byte(hex(m[5]))
+	l.PC = uint16(hex(m[6]))
+	l.S = byte(hex(m[7]))
+	l.P = byte(hex(m[8]))
+	return l, nil
+}
+
+// Diff returns the fields that differ between l and o, for example
+// "A=10/20". The PPU position is only compared if both lines have it.
+func (l TraceLine) Diff(o TraceLine) []string {
+	var res []string
+	add := func(name string, a, b int64, f string) {
+		if a != b {
+			res = append(res, fmt.Sprintf("%s="+f+"/"+f, name, a, b))
+		}
+	}
+	add("PC", int64(l.Addr), int64(o.Addr), "%04x")
+	add("A", int64(l.A), int64(o.A), "%02x")
+	add("X", int64(l.X), int64(o.X), "%02x")
+	add("Y", int64(l.Y), int64(o.Y), "%02x")
+	add("S", int64(l.S), int64(o.S), "%02x")
+	add("P", int64(l.P), int64(o.P), "%02x")
+	add("CYC", int64(l.Cyc), int64(o.Cyc), "%d")
+	if l.PPU && o.PPU {
+		add("SL", int64(l.Scanline), int64(o.Scanline), "%d")
+		add("DOT", int64(l.Dot), int64(o.Dot), "%d")
+	}
+	return res
+}
@@ -0,0 +1,52 @@
+package cpu6502
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Variant is a member of the 6502 family, see New
+type Variant byte
+
+const (
+	Variant2A03  Variant = iota // Ricoh 2A03 used by the NES, a NMOS 6502 without decimal mode
+	Variant6502                 // NMOS 6502, with decimal mode
+	Variant65C02                // WDC 65C02, CMOS with new instructions and some bugs fixed
+)
+
+// ParseVariant returns the variant with the given name, as returned by
+// Variant.String
+func ParseVariant(s string) (Variant, error) {
+	for _, v := range []Variant{Variant2A03, Variant6502, Variant65C02} {
+		if strings.EqualFold(s, v.String()) {
+			return v, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown CPU variant %q", s)
+}
+
+func (v Variant) String() string {
+	switch v {
+	case Variant2A03:
+		return "2A03"
+	case Variant6502:
+		return "6502"
+	case Variant65C02:
+		return "65C02"
+	default:
+		return fmt.Sprintf("Variant(%d)", byte(v))
+	}
+}
+
+// ops returns the opcode table of the variant
+func (v Variant) ops() *[256]*op {
+	if v == Variant65C02 {
+		return &cpu65c02op
+	}
+	return &cpu6502op
+}
+
+// decimal returns true if the variant implements decimal mode
+func (v Variant) decimal() bool {
+	return v != Variant2A03
+}
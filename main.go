@@ -5,31 +5,92 @@ import (
 	"image"
 	"log"
 	"os"
+	"os/signal"
 	"runtime/pprof"
+	"sync"
+	"syscall"
+	"time"
 
+	"github.com/MagicalTux/gones/cpu6502"
 	"github.com/MagicalTux/gones/nesapu"
 	"github.com/MagicalTux/gones/nescartridge"
+	"github.com/MagicalTux/gones/nesdebug"
 	"github.com/MagicalTux/gones/nesinput"
 	"github.com/MagicalTux/gones/pkgnes"
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/audio"
-	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
 )
 
 var (
 	cpuprofile = flag.String("cpuprofile", "", "write cpu profile to file (Go's pprof)")
 	cputrace   = flag.String("trace", "", "write 6502 instructions to file")
+	traceFmt   = flag.String("trace_format", "default", "format of the 6502 trace: default, or nestest (Nintendulator) for comparison with other emulators")
 	ppudebug   = flag.String("ppudebug", "", "write PPU (Picture Processing Unit) debug info to file, or - for stdout")
 	apudebug   = flag.String("apudebug", "", "write APU (Audio Processing Unit) debug info to file, or - for stdout")
 	zoom       = flag.Int("zoom", 4, "zoom level for display")
 	startV     = flag.Int("start_v", 0, "define start position in RAM, for ex 0xc000")
+	debug      = flag.Bool("debug", false, "start halted with an interactive debugger on the terminal")
+	gdb        = flag.String("gdb", "", "start halted and listen for GDB remote protocol connections on this address, for ex localhost:2345")
+	fourScore  = flag.Bool("fourscore", false, "connect a Four Score adapter for 4 players")
+	zapper     = flag.Bool("zapper", false, "connect a Zapper aimed with the mouse on port 2 instead of the second controller (not with -fourscore)")
+	inputCfg   = flag.String("input", "", "load input bindings from this JSON file, reloaded when it changes (see nesinput.Config)")
 )
 
 type Game struct {
 	nes     *pkgnes.NES
 	img     *ebiten.Image
 	started bool
-	gamepad ebiten.GamepadID
+
+	ports     *nesinput.Ports
+	showPorts time.Time        // show the devices of each port until then
+	zapper    *nesinput.Zapper // nil if no Zapper is connected
+
+	input      *nesinput.Config
+	inputMod   time.Time // modification time of *inputCfg when it was loaded
+	inputCheck time.Time // last time *inputCfg was checked for changes
+}
+
+// loadInput loads the input configuration from *inputCfg if set and changed
+// since it was last loaded, and returns true if it was loaded
+func (g *Game) loadInput() (bool, error) {
+	if *inputCfg == "" {
+		g.input = nesinput.DefaultConfig()
+		return true, nil
+	}
+	st, err := os.Stat(*inputCfg)
+	if err != nil {
+		return false, err
+	}
+	if g.input != nil && st.ModTime().Equal(g.inputMod) {
+		return false, nil
+	}
+	cfg, err := nesinput.LoadConfig(*inputCfg)
+	if err != nil {
+		return false, err
+	}
+	g.input, g.inputMod = cfg, st.ModTime()
+	return true, nil
+}
+
+// reloadInput reloads the input configuration if it changed, and applies the
+// new bindings to the ports
+func (g *Game) reloadInput() {
+	if *inputCfg == "" || time.Since(g.inputCheck) < time.Second {
+		return
+	}
+	g.inputCheck = time.Now()
+
+	loaded, err := g.loadInput()
+	if err != nil {
+		log.Printf("Failed to reload input configuration: %s", err)
+		return
+	}
+	if !loaded {
+		return
+	}
+	log.Printf("Input: reloaded %s", *inputCfg)
+	g.ports.SetConfig(g.input)
 }
 
 func (g *Game) Update() error {
@@ -51,25 +112,13 @@ func (g *Game) Update() error {
 		}
 	}
 
-	if g.gamepad != 0 {
-		if inpututil.IsGamepadJustDisconnected(g.gamepad) {
-			// return to keyboard control
-			g.nes.Input[0] = nesinput.NewKeyboard()
-			g.gamepad = 0
-		}
-	} else {
-		cn := inpututil.AppendJustConnectedGamepadIDs(nil)
-		if len(cn) > 0 {
-			// take first gamepad
-			id := cn[0]
-			pad, err := nesinput.NewGamepad(id)
-			if err != nil {
-				log.Printf("enable gamepad failed: %s", err)
-			} else {
-				g.gamepad = id
-				g.nes.Input[0] = pad
-			}
-		}
+	g.reloadInput()
+
+	if g.ports.Update() {
+		g.showPorts = time.Now().Add(3 * time.Second)
+	}
+	if g.zapper != nil {
+		g.zapper.Update()
 	}
 
 	return nil
@@ -80,6 +129,10 @@ func (g *Game) Draw(screen *ebiten.Image) {
 		g.img.WritePixels(img.Pix)
 	})
 	screen.DrawImage(g.img, nil)
+	if time.Now().Before(g.showPorts) || ebiten.IsKeyPressed(ebiten.KeyTab) {
+		// show which device is on which port after a change, or with Tab
+		ebitenutil.DebugPrint(screen, g.ports.String())
+	}
 }
 
 func (g *Game) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeight int) {
@@ -104,9 +157,43 @@ func main() {
 		os.Exit(1)
 	}
 
-	var err error
-	nes := pkgnes.New(pkgnes.NTSC)
-	nes.Input[0] = nesinput.NewKeyboard()
+	// load cartridge
+	data, err := nescartridge.Load(arg[0], nescartridge.WithBattery())
+	if err != nil {
+		log.Printf("Failed to load %s: %s", arg[0], err)
+		os.Exit(1)
+	}
+
+	nes := pkgnes.New(data.Model())
+
+	game := &Game{
+		nes: nes,
+		img: ebiten.NewImage(256, 240),
+	}
+	if _, err := game.loadInput(); err != nil {
+		log.Printf("Failed to load input configuration: %s", err)
+		os.Exit(1)
+	}
+	if *fourScore && *zapper {
+		log.Printf("A Zapper can't be connected with a Four Score")
+		os.Exit(1)
+	}
+	if *fourScore {
+		game.ports = nesinput.NewPorts(game.input, 4, nes.PPU)
+		fs := nesinput.NewFourScore(game.ports.Device(0), game.ports.Device(1), game.ports.Device(2), game.ports.Device(3))
+		nes.Input[0], nes.Input[1] = fs.Port(0), fs.Port(1)
+	} else if *zapper {
+		game.ports = nesinput.NewPorts(game.input, 1, nes.PPU)
+		nes.Input[0] = game.ports.Device(0)
+		game.zapper = nesinput.NewZapper(nes.PPU)
+		nes.Input[1] = game.zapper
+	} else {
+		game.ports = nesinput.NewPorts(game.input, len(nes.Input), nes.PPU)
+		for n := range nes.Input {
+			nes.Input[n] = game.ports.Device(n)
+		}
+	}
+	game.showPorts = time.Now().Add(3 * time.Second)
 
 	if *cputrace != "" {
 		nes.CPU.Trace, err = os.Create(*cputrace)
@@ -114,6 +201,14 @@ func main() {
 			log.Printf("Failed to create %s: %s", *cputrace, err)
 			os.Exit(1)
 		}
+		switch *traceFmt {
+		case "default":
+		case "nestest":
+			nes.CPU.TraceFormat = cpu6502.TraceNestest
+		default:
+			log.Printf("Unknown trace format %s", *traceFmt)
+			os.Exit(1)
+		}
 	}
 	if *ppudebug != "" {
 		if *ppudebug == "-" {
@@ -138,31 +233,58 @@ func main() {
 		}
 	}
 
-	// load cartridge
-	data, err := nescartridge.Load(arg[0])
-	if err != nil {
-		log.Printf("Failed to load %s: %s", arg[0], err)
-		os.Exit(1)
-	}
-
 	err = data.Setup(nes)
 	if err != nil {
 		log.Printf("Failed to map %s: %s", arg[0], err)
 		os.Exit(1)
 	}
 
+	if *debug {
+		dbg := nesdebug.New(nes)
+		dbg.Halt()
+		go func() {
+			if err := dbg.REPL(os.Stdin, os.Stdout); err != nil {
+				log.Printf("Debugger: %s", err)
+			}
+		}()
+	} else if *gdb != "" {
+		dbg := nesdebug.New(nes)
+		dbg.Halt()
+		go func() {
+			if err := dbg.ServeGDB(*gdb); err != nil {
+				log.Printf("GDB: %s", err)
+			}
+		}()
+	}
+
+	// stop emulation and release the cartridge, saving battery backed RAM if any
+	var closeOnce sync.Once
+	shutdown := func() {
+		closeOnce.Do(func() {
+			nes.Clk.Pause()
+			data.Close()
+		})
+	}
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		s := <-sig
+		log.Printf("Received %s, exiting", s)
+		shutdown()
+		os.Exit(1)
+	}()
+
 	log.Printf("CPU ready with memory: %s", nes.Memory)
 	log.Printf("PPU ready with memory: %s", nes.PPU.Memory)
 
 	ebiten.SetWindowSize(256*(*zoom), 240*(*zoom))
 	ebiten.SetWindowTitle("goNES")
 
-	game := &Game{
-		nes: nes,
-		img: ebiten.NewImage(256, 240),
-	}
+	err = ebiten.RunGame(game)
+	shutdown()
 
-	if err := ebiten.RunGame(game); err != nil {
+	if err != nil {
 		log.Fatal(err)
 	}
 }
@@ -2,12 +2,30 @@ package memory
 
 import (
 	"fmt"
+	"io"
 	"log"
 	"strings"
 	"unsafe"
 )
 
-type Bus [256][]Handler
+// Bus dispatches accesses to the handlers mapped on each 256 bytes page.
+//
+// Pages are flattened when their mapping changes so that the common cases
+// are fast: pages with a single handler call it directly, and pages with
+// only a RAM or ROM access its bytes without calling any handler. Reads from
+// pages without any handler return the last value seen on the bus (open bus).
+type Bus struct {
+	pages [256]busPage
+	last  byte // last value on the data bus
+}
+
+type busPage struct {
+	handlers []Handler
+	single   Handler // the handler if it is the only one
+	read     []byte  // bytes of the page if it only has a RAM or ROM
+	write    []byte  // bytes of the page if it only has a RAM
+	watches  []*Watch
+}
 
 func NewBus() Master {
 	return &Bus{}
@@ -21,7 +39,9 @@ func (b *Bus) MapHandler(offset uint16, length uint16, h Handler) {
 	}
 
 	for i := uint16(0); i < cnt; i++ {
-		b[offt+i] = append(b[offt+i], h)
+		p := &b.pages[offt+i]
+		p.handlers = append(p.handlers, h)
+		p.update(offt + i)
 	}
 }
 
@@ -33,41 +53,187 @@ func (b *Bus) ClearMapping(offset, length uint16) {
 	}
 
 	for i := uint16(0); i < cnt; i++ {
-		b[offt+i] = nil
+		p := &b.pages[offt+i]
+		p.handlers = nil
+		p.update(offt + i)
 	}
 }
 
-func (b Bus) MemRead(offset uint16) byte {
-	offt := offset >> 8
-	var res byte
+// update computes the fast paths of page n after its handlers changed
+func (p *busPage) update(n uint16) {
+	p.single, p.read, p.write = nil, nil, nil
+	if len(p.handlers) != 1 {
+		return
+	}
+	p.single = p.handlers[0]
 
-	for _, h := range b[offt] {
-		res |= h.MemRead(offset)
+	// RAM and ROM mask the offset with their length, which must be a power
+	// of two of at least a page for their bytes to be accessed directly
+	switch v := p.single.(type) {
+	case RAM:
+		p.read = pageBytes(v, n)
+		p.write = p.read
+	case ROM:
+		p.read = pageBytes(v, n)
 	}
+}
 
-	return res
+// pageBytes returns the bytes of mem seen at page n, or nil if mem's length
+// doesn't allow it
+func pageBytes(mem []byte, n uint16) []byte {
+	ln := len(mem)
+	if ln < 0x100 || ln > 0x10000 || ln&(ln-1) != 0 {
+		return nil
+	}
+	start := int(n<<8) & (ln - 1)
+	return mem[start : start+0x100 : start+0x100]
 }
 
-func (b Bus) MemWrite(offset uint16, val byte) byte {
-	offt := offset >> 8
+func (b *Bus) MemRead(offset uint16) byte {
+	p := &b.pages[offset>>8]
 	var res byte
-	var cnt int
 
-	for _, h := range b[offt] {
-		res = h.MemWrite(offset, val)
-		cnt += 1
+	switch {
+	case p.read != nil:
+		res = p.read[offset&0xff]
+	case p.single != nil:
+		res = p.single.MemRead(offset)
+	case len(p.handlers) == 0:
+		// open bus
+		res = b.last
+	default:
+		for _, h := range p.handlers {
+			res |= h.MemRead(offset)
+		}
 	}
-	if res != val && cnt > 1 && res != 0 {
-		// see: https://www.nesdev.org/wiki/Bus_conflict
-		// We don't overwrite val with this yet, but we might if it is required
-		// do not show a warning if only 1 device, because it could be a ROM that someone is trying to write to
-		log.Printf("Bus conflict at address $%04x! Write=$%02x but got bits $%02x", offset, val, res)
+	for _, w := range p.watches {
+		w.access(Access{Addr: offset, Value: res})
 	}
+
+	b.last = res
 	return res
 }
 
-func (b Bus) Ptr() uintptr {
-	return uintptr(unsafe.Pointer(&b))
+// MemWrite writes val to all the handlers mapped at the given offset, and
+// returns the value that was actually written on the bus.
+//
+// If a ROM shares the page with other handlers, it will drive the bus at the
+// same time as the CPU and handlers mapped after it will receive the AND of
+// both values. Mappers can choose to emulate bus conflicts or not by mapping
+// their registers after or before the ROM.
+func (b *Bus) MemWrite(offset uint16, val byte) byte {
+	p := &b.pages[offset>>8]
+
+	switch {
+	case p.write != nil:
+		p.write[offset&0xff] = val
+	case p.single != nil:
+		p.single.MemWrite(offset, val)
+	default:
+		for n, h := range p.handlers {
+			res := h.MemWrite(offset, val)
+			if _, isROM := h.(ROM); isROM && res != val && n < len(p.handlers)-1 {
+				// see: https://www.nesdev.org/wiki/Bus_conflict
+				log.Printf("Bus conflict at address $%04x! Write=$%02x but got bits $%02x", offset, val, res)
+				val &= res
+			}
+		}
+	}
+	for _, w := range p.watches {
+		w.access(Access{Addr: offset, Value: val, Write: true})
+	}
+
+	b.last = val
+	return val
+}
+
+// Peek returns the value MemRead would return at the given offset, without
+// changing the value of the open bus, calling watches, or causing the side
+// effects of reading handlers that implement Peeker.
+func (b *Bus) Peek(offset uint16) byte {
+	p := &b.pages[offset>>8]
+
+	switch {
+	case p.read != nil:
+		return p.read[offset&0xff]
+	case p.single != nil:
+		return Peek(p.single, offset)
+	case len(p.handlers) == 0:
+		return b.last
+	default:
+		var res byte
+		for _, h := range p.handlers {
+			res |= Peek(h, offset)
+		}
+		return res
+	}
+}
+
+// Peek reads h at the given offset without side effects if it implements
+// Peeker, or with MemRead otherwise
+func Peek(h Handler, offset uint16) byte {
+	if p, ok := h.(Peeker); ok {
+		return p.Peek(offset)
+	}
+	return h.MemRead(offset)
+}
+
+// OpenBus returns the last value seen on the data bus. Handlers that don't
+// drive all the bits of the bus use it for the other bits, see OpenBus.
+func (b *Bus) OpenBus() byte {
+	return b.last
+}
+
+// OpenBus returns the last value seen on the data bus of m, or 0 if m
+// doesn't track it
+func OpenBus(m Master) byte {
+	if ob, ok := m.(interface{ OpenBus() byte }); ok {
+		return ob.OpenBus()
+	}
+	return 0
+}
+
+// SaveState writes the state of the bus, which is the value of the open bus
+func (b *Bus) SaveState(w io.Writer) error {
+	_, err := w.Write([]byte{b.last})
+	return err
+}
+
+// LoadState restores the state written by SaveState
+func (b *Bus) LoadState(r io.Reader) error {
+	var buf [1]byte
+	if _, err := io.ReadFull(r, buf[:]); err != nil {
+		return err
+	}
+	b.last = buf[0]
+	return nil
+}
+
+func (b *Bus) Ptr() uintptr {
+	return uintptr(unsafe.Pointer(b))
+}
+
+// AddWatch installs w on the pages of the bus it covers, so that all accesses
+// made through the bus are reported to w, including to handlers mapped later.
+// Watches must not be added or removed while the bus is in use, see
+// pkgnes.NES.AddWatch.
+func (b *Bus) AddWatch(w *Watch) {
+	for i := w.Start >> 8; i <= w.End>>8; i++ {
+		b.pages[i].watches = append(b.pages[i].watches, w)
+	}
+}
+
+// RemoveWatch removes a watch installed by AddWatch
+func (b *Bus) RemoveWatch(w *Watch) {
+	for i := range b.pages {
+		p := &b.pages[i]
+		for n, v := range p.watches {
+			if v == w {
+				p.watches = append(p.watches[:n:n], p.watches[n+1:]...)
+				break
+			}
+		}
+	}
 }
 
 type debugInfo struct {
@@ -76,13 +242,13 @@ type debugInfo struct {
 	end   uint16
 }
 
-func (b Bus) String() string {
+func (b *Bus) String() string {
 	var m []*debugInfo
 
-	for n, v := range b {
+	for n, p := range b.pages {
 
 	loop1:
-		for _, h := range v {
+		for _, h := range p.handlers {
 			// TODO handle when same object is mapped at multiple places
 			for _, i := range m {
 				if i.h.Ptr() == h.Ptr() && i.end == uint16(n) {
@@ -0,0 +1,360 @@
+package memory
+
+import (
+	"testing"
+)
+
+// legacyBus is the previous design of Bus, kept to compare performance
+type legacyBus [256][]Handler
+
+func (b *legacyBus) MapHandler(offset uint16, length uint16, h Handler) {
+	offt := offset >> 8
+	cnt := length >> 8
+	if length%0x100 != 0 {
+		cnt += 1
+	}
+
+	for i := uint16(0); i < cnt; i++ {
+		b[offt+i] = append(b[offt+i], h)
+	}
+}
+
+func (b *legacyBus) ClearMapping(offset, length uint16) {
+	offt := offset >> 8
+	cnt := length >> 8
+	if length%0x100 != 0 {
+		cnt += 1
+	}
+
+	for i := uint16(0); i < cnt; i++ {
+		b[offt+i] = nil
+	}
+}
+
+func (b legacyBus) MemRead(offset uint16) byte {
+	offt := offset >> 8
+	var res byte
+
+	for _, h := range b[offt] {
+		res |= h.MemRead(offset)
+	}
+
+	return res
+}
+
+func (b legacyBus) MemWrite(offset uint16, val byte) byte {
+	offt := offset >> 8
+	handlers := b[offt]
+
+	for n, h := range handlers {
+		res := h.MemWrite(offset, val)
+		if _, isROM := h.(ROM); isROM && res != val && n < len(handlers)-1 {
+			val &= res
+		}
+	}
+	return val
+}
+
+func (b *legacyBus) Length() uint16 { return 0 }
+func (b *legacyBus) Ptr() uintptr   { return 0 }
+
+// register is a device with registers, such as the PPU or a mapper
+type register struct {
+	v [8]byte
+}
+
+func (r *register) MemRead(offset uint16) byte          { return r.v[offset&7] }
+func (r *register) MemWrite(offset uint16, v byte) byte { r.v[offset&7] = v; return v }
+func (r *register) Length() uint16                      { return 8 }
+func (r *register) Ptr() uintptr                        { return 0 }
+
+// newBenchBus maps handlers the way the NES does: RAM and its mirrors, a
+// device with registers, PRG RAM, and a ROM with a mapper's registers on top
+// of the upper half
+func newBenchBus(b Master) Master {
+	b.MapHandler(0x0000, 0x2000, NewRAM(0x800))
+	b.MapHandler(0x2000, 0x2000, &register{})
+	b.MapHandler(0x6000, 0x2000, NewRAM(0x2000))
+	b.MapHandler(0x8000, 0x8000, make(ROM, 0x8000))
+	b.MapHandler(0xc000, 0x4000, &register{})
+	return b
+}
+
+var benchBuses = []struct {
+	name string
+	new  func() Master
+}{
+	{"legacy", func() Master { return &legacyBus{} }},
+	{"bus", NewBus},
+}
+
+func benchmarkRead(b *testing.B, start, length int) {
+	for _, bb := range benchBuses {
+		bus := newBenchBus(bb.new())
+		b.Run(bb.name, func(b *testing.B) {
+			var res byte
+			for i := 0; i < b.N; i++ {
+				res ^= bus.MemRead(uint16(start + i%length))
+			}
+			_ = res
+		})
+	}
+}
+
+func benchmarkWrite(b *testing.B, start, length int) {
+	for _, bb := range benchBuses {
+		bus := newBenchBus(bb.new())
+		b.Run(bb.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				bus.MemWrite(uint16(start+i%length), byte(i))
+			}
+		})
+	}
+}
+
+func BenchmarkReadRAM(b *testing.B) {
+	benchmarkRead(b, 0x0000, 0x800)
+}
+
+func BenchmarkWriteRAM(b *testing.B) {
+	benchmarkWrite(b, 0x0000, 0x800)
+}
+
+func BenchmarkReadROM(b *testing.B) {
+	benchmarkRead(b, 0x8000, 0x4000)
+}
+
+func BenchmarkReadRegister(b *testing.B) {
+	benchmarkRead(b, 0x2000, 8)
+}
+
+func BenchmarkReadMapper(b *testing.B) {
+	// ROM and mapper registers on the same pages
+	benchmarkRead(b, 0xc000, 0x4000)
+}
+
+func BenchmarkReadUnmapped(b *testing.B) {
+	benchmarkRead(b, 0x4000, 0x2000)
+}
+
+// countReg is a register counting its reads, such as a status register
+// clearing a flag when read
+type countReg struct {
+	Null
+	reads int
+}
+
+func (r *countReg) MemRead(offset uint16) byte {
+	r.reads++
+	return byte(r.reads)
+}
+
+func (r *countReg) Peek(offset uint16) byte {
+	return byte(r.reads)
+}
+
+func TestBusPeek(t *testing.T) {
+	b := NewBus().(*Bus)
+	ram := NewRAM(0x800)
+	reg := &countReg{}
+	b.MapHandler(0x0000, 0x2000, ram)
+	b.MapHandler(0x2000, 0x100, reg)
+
+	w := &Watch{Start: 0, End: 0xffff, Read: true}
+	b.AddWatch(w)
+
+	ram[0x123] = 0x42
+	b.MemWrite(0x4000, 0x99) // open bus is now $99
+
+	tests := []struct {
+		addr uint16
+		want byte
+	}{
+		{0x0123, 0x42},
+		{0x0923, 0x42}, // mirror
+		{0x2000, 0x00},
+		{0x5000, 0x99}, // open bus
+	}
+	for _, tt := range tests {
+		if v := b.Peek(tt.addr); v != tt.want {
+			t.Errorf("Peek($%04x) = $%02x, want $%02x", tt.addr, v, tt.want)
+		}
+	}
+
+	if reg.reads != 0 {
+		t.Errorf("register was read %d times", reg.reads)
+	}
+	if b.OpenBus() != 0x99 {
+		t.Errorf("open bus changed to $%02x", b.OpenBus())
+	}
+	if r, _ := w.Count(); r != 0 {
+		t.Errorf("watch saw %d reads", r)
+	}
+}
+
+// testROM returns a ROM of the given size filled with a pattern
+func testROM(size int) ROM {
+	rom := make(ROM, size)
+	for n := range rom {
+		rom[n] = byte(n*7 + 1)
+	}
+	return rom
+}
+
+var busLayouts = []struct {
+	name  string
+	setup func(b Master)
+	paths map[uint16]string // expected fast path of some pages
+}{
+	{
+		name: "NES",
+		setup: func(b Master) {
+			b.MapHandler(0x0000, 0x2000, NewRAM(0x800))
+			b.MapHandler(0x2000, 0x2000, &register{})
+			b.MapHandler(0x6000, 0x2000, NewRAM(0x2000))
+			// mapper registers after the ROM see bus conflicts
+			b.MapHandler(0x8000, 0x8000, testROM(0x8000))
+			b.MapHandler(0xc000, 0x4000, &register{})
+		},
+		paths: map[uint16]string{0x00: "ram", 0x1f: "ram", 0x20: "single", 0x40: "none", 0x7f: "ram", 0x80: "rom", 0xc0: "multi", 0xff: "multi"},
+	},
+	{
+		name: "small",
+		setup: func(b Master) {
+			// less than a page of RAM, mirrored in its page
+			b.MapHandler(0x0000, 0x100, NewRAM(0x80))
+			// a page of PRG RAM mapped on half a page
+			b.MapHandler(0x6000, 0x80, NewRAM(0x100))
+			// a ROM mirrored on all pages
+			b.MapHandler(0x8000, 0x4000, testROM(0x100))
+			// mapper registers before the ROM see the CPU's value
+			b.MapHandler(0xc000, 0x4000, &register{})
+			b.MapHandler(0xc000, 0x4000, testROM(0x4000))
+		},
+		paths: map[uint16]string{0x00: "single", 0x01: "none", 0x60: "ram", 0x61: "none", 0x80: "rom", 0xbf: "rom", 0xc0: "multi"},
+	},
+}
+
+// busPath returns the fast path used by page p
+func busPath(p *busPage) string {
+	switch {
+	case p.write != nil:
+		return "ram"
+	case p.read != nil:
+		return "rom"
+	case p.single != nil:
+		return "single"
+	case len(p.handlers) == 0:
+		return "none"
+	default:
+		return "multi"
+	}
+}
+
+func TestBusLegacy(t *testing.T) {
+	writes := []uint16{
+		0x0000, 0x007f, 0x0080, 0x00ff, 0x0100, 0x07ff, 0x0800, 0x1fff,
+		0x2000, 0x2007, 0x2008, 0x3fff, 0x5000,
+		0x6000, 0x607f, 0x6080, 0x60ff, 0x7fff,
+		0x8000, 0x80ff, 0xbfff, 0xc000, 0xc001, 0xc007, 0xdfff, 0xffff,
+	}
+
+	for _, tt := range busLayouts {
+		t.Run(tt.name, func(t *testing.T) {
+			bus := NewBus().(*Bus)
+			legacy := &legacyBus{}
+			tt.setup(bus)
+			tt.setup(legacy)
+
+			for page, want := range tt.paths {
+				if got := busPath(&bus.pages[page]); got != want {
+					t.Errorf("page $%02x uses %s, want %s", page, got, want)
+				}
+			}
+
+			for n, addr := range writes {
+				val := byte(n*0x35 + 0x5a)
+				got, want := bus.MemWrite(addr, val), legacy.MemWrite(addr, val)
+				if got != want {
+					t.Errorf("MemWrite($%04x, $%02x) = $%02x, want $%02x", addr, val, got, want)
+				}
+			}
+
+			for addr := 0; addr < 0x10000; addr++ {
+				if len(legacy[addr>>8]) == 0 {
+					// unmapped, see TestBusOpenBus
+					continue
+				}
+				got, want := bus.MemRead(uint16(addr)), legacy.MemRead(uint16(addr))
+				if got != want {
+					t.Errorf("MemRead($%04x) = $%02x, want $%02x", addr, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestBusConflicts(t *testing.T) {
+	rom := testROM(0x8000)
+
+	tests := []struct {
+		name string
+		addr uint16
+		val  byte
+		want byte // value returned by MemWrite and seen by the register
+	}{
+		{"same value", 0xc003, rom[0x4003], rom[0x4003]},
+		{"all bits", 0xc003, 0xff, rom[0x4003]},
+		{"some bits", 0xc005, 0x0f, 0x0f & rom[0x4005]},
+		{"ROM only", 0x8003, 0x00, 0x00},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bus := NewBus()
+			reg := &register{}
+			bus.MapHandler(0x8000, 0x8000, rom)
+			bus.MapHandler(0xc000, 0x4000, reg)
+
+			if got := bus.MemWrite(tt.addr, tt.val); got != tt.want {
+				t.Errorf("MemWrite($%04x, $%02x) = $%02x, want $%02x", tt.addr, tt.val, got, tt.want)
+			}
+			if tt.addr < 0xc000 {
+				return
+			}
+			if reg.v[tt.addr&7] != tt.want {
+				t.Errorf("register got $%02x, want $%02x", reg.v[tt.addr&7], tt.want)
+			}
+			// both drive the bus when read
+			if v, want := bus.MemRead(tt.addr), rom[tt.addr&0x7fff]|tt.want; v != want {
+				t.Errorf("MemRead($%04x) = $%02x, want $%02x", tt.addr, v, want)
+			}
+		})
+	}
+}
+
+func TestBusOpenBus(t *testing.T) {
+	bus := NewBus().(*Bus)
+	bus.MapHandler(0x0000, 0x2000, NewRAM(0x800))
+
+	bus.MemWrite(0x0010, 0x42)
+	if v := bus.MemRead(0x5000); v != 0x42 {
+		t.Errorf("unmapped read after write = $%02x, want $42", v)
+	}
+	bus.MemWrite(0x4000, 0x17)
+	if v := bus.MemRead(0x4000); v != 0x17 {
+		t.Errorf("unmapped read after unmapped write = $%02x, want $17", v)
+	}
+	if v := bus.MemRead(0x0010); v != 0x42 || bus.OpenBus() != 0x42 {
+		t.Errorf("read = $%02x with open bus $%02x, want $42", v, bus.OpenBus())
+	}
+
+	// a page left without handlers by ClearMapping is open bus too
+	bus.ClearMapping(0x0000, 0x800)
+	if v := bus.MemRead(0x0010); v != 0x42 {
+		t.Errorf("cleared read = $%02x, want $42", v)
+	}
+	if v := bus.MemRead(0x0810); v != 0x42 {
+		t.Errorf("mirror read = $%02x, want $42", v)
+	}
+}
@@ -12,3 +12,10 @@ type Master interface {
 	MapHandler(offset uint16, length uint16, h Handler)
 	ClearMapping(offset, length uint16)
 }
+
+// Peeker is implemented by handlers whose reads have side effects, such as
+// registers clearing a flag when read. Peek returns what MemRead would return
+// without changing anything, for debuggers and tracers. See Peek.
+type Peeker interface {
+	Peek(offset uint16) byte
+}
@@ -30,11 +30,15 @@ type memSlice struct {
 }
 
 func (ms *memSlice) MemRead(offset uint16) byte {
-	return ms.dev.MemRead((ms.offset & ms.mask) + ms.offset)
+	return ms.dev.MemRead((offset & ms.mask) + ms.offset)
+}
+
+func (ms *memSlice) Peek(offset uint16) byte {
+	return Peek(ms.dev, (offset&ms.mask)+ms.offset)
 }
 
 func (ms *memSlice) MemWrite(offset uint16, v byte) byte {
-	return ms.dev.MemWrite((ms.offset&ms.mask)+ms.offset, v)
+	return ms.dev.MemWrite((offset&ms.mask)+ms.offset, v)
 }
 
 func (ms *memSlice) Length() uint16 {
@@ -0,0 +1,112 @@
+package memory
+
+import (
+	"fmt"
+	"io"
+	"sync/atomic"
+)
+
+// Access is a read or write seen by a Watch
+type Access struct {
+	Addr  uint16
+	Value byte // value read or written
+	Write bool
+}
+
+func (a Access) String() string {
+	if a.Write {
+		return fmt.Sprintf("write $%02x to $%04x", a.Value, a.Addr)
+	}
+	return fmt.Sprintf("read $%02x from $%04x", a.Value, a.Addr)
+}
+
+// Watch observes the accesses made to handlers without changing them, to
+// log, count or call a function on the accesses that match its filters. It
+// can wrap any handler with Wrap, or be installed on a range of a Bus with
+// Bus.AddWatch.
+//
+// For example, to find who writes to $0300:
+//
+//	nes.AddWatch(&memory.Watch{Start: 0x300, End: 0x300, Write: true, Func: func(a memory.Access) {
+//		log.Printf("%s near PC=$%04x", a, nes.CPU.PC)
+//	}})
+type Watch struct {
+	Start, End  uint16 // addresses to watch, inclusive
+	Read, Write bool   // kinds of accesses to watch
+
+	// Mask and Value filter accesses on the value read or written: only
+	// accesses where value&Mask == Value match. A zero Mask matches all
+	// values, whatever Value is.
+	Mask, Value byte
+
+	Func func(a Access) // if set, called on each matching access
+	Log  io.Writer      // if set, each matching access is written there
+
+	reads, writes uint64
+}
+
+// Count returns the number of matching reads and writes so far
+func (w *Watch) Count() (reads, writes uint64) {
+	return atomic.LoadUint64(&w.reads), atomic.LoadUint64(&w.writes)
+}
+
+func (w *Watch) match(a Access) bool {
+	if a.Addr < w.Start || a.Addr > w.End {
+		return false
+	}
+	if a.Write && !w.Write || !a.Write && !w.Read {
+		return false
+	}
+	return w.Mask == 0 || a.Value&w.Mask == w.Value
+}
+
+func (w *Watch) access(a Access) {
+	if !w.match(a) {
+		return
+	}
+	if a.Write {
+		atomic.AddUint64(&w.writes, 1)
+	} else {
+		atomic.AddUint64(&w.reads, 1)
+	}
+	if w.Log != nil {
+		fmt.Fprintf(w.Log, "%s\n", a)
+	}
+	if w.Func != nil {
+		w.Func(a)
+	}
+}
+
+func (w *Watch) String() string {
+	return fmt.Sprintf("Watch($%04x~$%04x)", w.Start, w.End)
+}
+
+// Wrap returns a handler that reports accesses to h to w
+func (w *Watch) Wrap(h Handler) Handler {
+	return &watchHandler{Handler: h, w: w}
+}
+
+type watchHandler struct {
+	Handler
+	w *Watch
+}
+
+func (wh *watchHandler) MemRead(offset uint16) byte {
+	v := wh.Handler.MemRead(offset)
+	wh.w.access(Access{Addr: offset, Value: v})
+	return v
+}
+
+func (wh *watchHandler) Peek(offset uint16) byte {
+	return Peek(wh.Handler, offset)
+}
+
+func (wh *watchHandler) MemWrite(offset uint16, val byte) byte {
+	res := wh.Handler.MemWrite(offset, val)
+	wh.w.access(Access{Addr: offset, Value: val, Write: true})
+	return res
+}
+
+func (wh *watchHandler) String() string {
+	return fmt.Sprintf("%s of %s", wh.w, wh.Handler)
+}
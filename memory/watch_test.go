@@ -0,0 +1,65 @@
+package memory
+
+import "testing"
+
+func TestWatchMatch(t *testing.T) {
+	tests := []struct {
+		name string
+		w    Watch
+		a    Access
+		want bool
+	}{
+		{"in range", Watch{Start: 0x300, End: 0x3ff, Read: true}, Access{Addr: 0x310}, true},
+		{"before range", Watch{Start: 0x300, End: 0x3ff, Read: true}, Access{Addr: 0x2ff}, false},
+		{"after range", Watch{Start: 0x300, End: 0x3ff, Read: true}, Access{Addr: 0x400}, false},
+		{"read only", Watch{Start: 0x300, End: 0x300, Read: true}, Access{Addr: 0x300, Write: true}, false},
+		{"write only", Watch{Start: 0x300, End: 0x300, Write: true}, Access{Addr: 0x300}, false},
+		{"mask match", Watch{Start: 0x300, End: 0x300, Write: true, Mask: 0x80, Value: 0x80}, Access{Addr: 0x300, Value: 0xc1, Write: true}, true},
+		{"mask mismatch", Watch{Start: 0x300, End: 0x300, Write: true, Mask: 0x80, Value: 0x80}, Access{Addr: 0x300, Value: 0x41, Write: true}, false},
+		{"zero mask", Watch{Start: 0x300, End: 0x300, Write: true}, Access{Addr: 0x300, Value: 0x41, Write: true}, true},
+		{"zero mask with value", Watch{Start: 0x300, End: 0x300, Write: true, Value: 0x12}, Access{Addr: 0x300, Value: 0x41, Write: true}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.w.match(tt.a); got != tt.want {
+				t.Errorf("match(%s) = %v, want %v", tt.a, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBusWatch(t *testing.T) {
+	bus := NewBus().(*Bus)
+	bus.MapHandler(0x0000, 0x2000, NewRAM(0x800))
+
+	var seen []Access
+	w := &Watch{Start: 0x0300, End: 0x0301, Read: true, Write: true, Func: func(a Access) {
+		seen = append(seen, a)
+	}}
+	bus.AddWatch(w)
+
+	bus.MemWrite(0x0300, 0x12)
+	bus.MemWrite(0x0302, 0x34) // out of range
+	bus.MemRead(0x0300)
+	bus.Peek(0x0301) // peeking is not an access
+
+	want := []Access{{Addr: 0x0300, Value: 0x12, Write: true}, {Addr: 0x0300, Value: 0x12}}
+	if len(seen) != len(want) {
+		t.Fatalf("saw %v, want %v", seen, want)
+	}
+	for n := range want {
+		if seen[n] != want[n] {
+			t.Errorf("access %d is %s, want %s", n, seen[n], want[n])
+		}
+	}
+	if r, w := w.Count(); r != 1 || w != 1 {
+		t.Errorf("Count() = %d, %d, want 1, 1", r, w)
+	}
+
+	bus.RemoveWatch(w)
+	bus.MemRead(0x0300)
+	if len(seen) != len(want) {
+		t.Errorf("watch called after being removed")
+	}
+}
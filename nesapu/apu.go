@@ -9,11 +9,21 @@ import (
 )
 
 type APU struct {
-	Memory    memory.Master
-	Input     [2]InputDevice // we put inputs here since the APU's buffer is used to talk to them
-	Interrupt func()
-	cpuDelay  func(uint64) uint64
-	Trace     io.Writer
+	Memory  memory.Master
+	Input   [2]InputDevice // we put inputs here since the APU's buffer is used to talk to them
+	Trace   io.Writer
+	NoAudio bool // drop samples instead of buffering them, for when nothing reads audio (headless)
+
+	// FrameIRQ and DMCIRQ are called when the frame counter or the DMC
+	// channel assert (true) or acknowledge (false) their interrupt
+	FrameIRQ func(asserted bool)
+	DMCIRQ   func(asserted bool)
+
+	// OAMDMA and DMCDMA start DMA transfers on the CPU's DMA unit, for
+	// writes to $4014 and DMC sample fetches. DMC samples are passed back
+	// to DMCSample.
+	OAMDMA func(page byte)
+	DMCDMA func(addr uint16)
 
 	channel chan float32
 
@@ -34,10 +44,9 @@ type APU struct {
 	interruptFlag  bool
 }
 
-func New(mem memory.Master, t func(uint64) uint64) *APU {
+func New(mem memory.Master) *APU {
 	res := &APU{
 		Memory:   mem,
-		cpuDelay: t,
 		channel:  make(chan float32, bufferedSamples*4),
 		pulse1:   &Pulse{channel: 1},
 		pulse2:   &Pulse{channel: 2},
@@ -103,10 +112,7 @@ func (apu *APU) stepFrameCounter() {
 			apu.stepSweep()
 			apu.stepLength()
 			if apu.frameIRQ {
-				apu.interruptFlag = true
-				if i := apu.Interrupt; i != nil {
-					i()
-				}
+				apu.setFrameInterrupt(true)
 			}
 		}
 	case 1:
@@ -154,6 +160,17 @@ func (apu *APU) stepLength() {
 func (apu *APU) readStatus() byte {
 	// Reading this register clears the frame interrupt flag (but not the DMC interrupt flag).
 	// If an interrupt flag was set at the same moment of the read, it will read back as 1 but it will not be cleared.
+	res := apu.status()
+	if apu.interruptFlag {
+		apu.setFrameInterrupt(false)
+	}
+	// $4015   if-d nt21   DMC IRQ, frame IRQ, length counter statuses
+	log.Printf("APU: Read status = $%02x", res)
+	return res
+}
+
+// status returns the value of $4015, see readStatus
+func (apu *APU) status() byte {
 	var res byte
 	if apu.pulse1.lengthValue > 0 {
 		res |= 0x01
@@ -172,13 +189,10 @@ func (apu *APU) readStatus() byte {
 	}
 	if apu.interruptFlag {
 		res |= 0x40
-		apu.interruptFlag = false
 	}
 	if apu.dmc.irqFlag {
 		res |= 0x80
 	}
-	// $4015   if-d nt21   DMC IRQ, frame IRQ, length counter statuses
-	log.Printf("APU: Read status = $%02x", res)
 	return res
 }
 
@@ -201,7 +215,7 @@ func (apu *APU) writeControl(value byte) {
 	if !apu.noise.enabled {
 		apu.noise.lengthValue = 0
 	}
-	apu.dmc.irqFlag = false
+	apu.dmc.setInterrupt(false)
 	if !apu.dmc.enabled {
 		apu.dmc.currentLength = 0
 	} else {
@@ -215,7 +229,7 @@ func (apu *APU) writeFrameCounter(value byte) {
 	apu.frameMode = (value >> 7) & 1
 	apu.frameIRQ = (value>>6)&1 == 0
 	if !apu.frameIRQ {
-		apu.interruptFlag = false
+		apu.setFrameInterrupt(false)
 	}
 	// apu.frameValue = 0
 	if apu.frameMode == 1 {
@@ -225,6 +239,14 @@ func (apu *APU) writeFrameCounter(value byte) {
 	}
 }
 
+// setFrameInterrupt sets the frame interrupt flag, which drives the IRQ line
+func (apu *APU) setFrameInterrupt(v bool) {
+	apu.interruptFlag = v
+	if f := apu.FrameIRQ; f != nil {
+		f(v)
+	}
+}
+
 func (apu *APU) trace(msg string, arg ...any) {
 	if apu.Trace == nil {
 		return
@@ -15,6 +15,7 @@ type DMC struct {
 	loop           bool
 	irq            bool
 	irqFlag        bool
+	fetching       bool // waiting for DMCSample
 }
 
 func (d *DMC) MemWrite(addr uint16, val byte) byte {
@@ -38,7 +39,7 @@ func (d *DMC) MemWrite(addr uint16, val byte) byte {
 func (d *DMC) writeControl(value byte) {
 	d.irq = value&0x80 == 0x80
 	if !d.irq {
-		d.irqFlag = false
+		d.setInterrupt(false)
 	}
 	d.loop = value&0x40 == 0x40
 	d.tickPeriod = dmcTable[value&0x0F]
@@ -77,28 +78,53 @@ func (d *DMC) stepTimer() {
 }
 
 func (d *DMC) stepReader() {
-	if d.currentLength > 0 && d.bitCount == 0 {
-		d.apu.cpuDelay(4)
-		d.shiftRegister = d.apu.Memory.MemRead(d.currentAddress)
-		d.bitCount = 8
-		d.currentAddress++
-		if d.currentAddress == 0 {
-			d.currentAddress = 0x8000
+	if d.currentLength > 0 && d.bitCount == 0 && !d.fetching {
+		if d.apu.DMCDMA == nil {
+			// no DMA unit, read immediately
+			d.sample(d.apu.Memory.MemRead(d.currentAddress))
+			return
 		}
-		d.currentLength--
-		if d.currentLength == 0 {
-			if d.loop {
-				d.restart()
-			} else if d.irq {
-				d.irqFlag = true
-				if i := d.apu.Interrupt; i != nil {
-					i()
-				}
-			}
+		d.fetching = true
+		d.apu.DMCDMA(d.currentAddress)
+	}
+}
+
+// DMCSample receives the sample byte fetched by the DMA unit for the DMC
+// channel, see DMCDMA
+func (apu *APU) DMCSample(v byte) {
+	apu.dmc.sample(v)
+}
+
+func (d *DMC) sample(v byte) {
+	d.fetching = false
+	d.shiftRegister = v
+	d.bitCount = 8
+	d.currentAddress++
+	if d.currentAddress == 0 {
+		d.currentAddress = 0x8000
+	}
+	if d.currentLength == 0 {
+		// channel was disabled during the fetch
+		return
+	}
+	d.currentLength--
+	if d.currentLength == 0 {
+		if d.loop {
+			d.restart()
+		} else if d.irq {
+			d.setInterrupt(true)
 		}
 	}
 }
 
+// setInterrupt sets the DMC interrupt flag, which drives the IRQ line
+func (d *DMC) setInterrupt(v bool) {
+	d.irqFlag = v
+	if f := d.apu.DMCIRQ; f != nil {
+		f(v)
+	}
+}
+
 func (d *DMC) stepShifter() {
 	if d.bitCount == 0 {
 		return
@@ -3,35 +3,47 @@ package nesapu
 import (
 	"log"
 	"unsafe"
+
+	"github.com/MagicalTux/gones/memory"
 )
 
 func (apu *APU) MemRead(offset uint16) byte {
 	offset &= 0x1fff
 	switch offset {
 	case 0x15: // status
-		return apu.readStatus()
-	case 0x16: // read from input 0
-		if dev := apu.Input[0]; dev != nil {
-			return dev.Read()
-		}
-		return 0
-	case 0x17: // read from input 1
-		if dev := apu.Input[1]; dev != nil {
-			return dev.Read()
+		// bit 5 isn't driven
+		return apu.readStatus() | apu.openBus()&0x20
+	case 0x16, 0x17: // read from input 0 or 1
+		// input devices drive bits 0~4, the others are open bus
+		// See: https://www.nesdev.org/wiki/Standard_controller#Output_($4016/$4017_read)
+		res := apu.openBus() & 0xe0
+		if dev := apu.Input[offset-0x16]; dev != nil {
+			res |= dev.Read() & 0x1f
 		}
-		return 0
+		return res
 	default:
-		if offset < 0x15 {
-			// https://github.com/quackenbush/nestalgia/blob/master/docs/apu/nessound.txt
-			// Note that $4015 is the only R/W register. All others are write only (attempt
-			// to read them will most likely result in a returned 040H, due to heavy
-			// capacitance on the NES's data bus). Reading a "write only" register, will
-			// have no effect on the specific register, or channel.
-			return 0x40
-		}
-		log.Printf("Unhandled APU read: $%04x", offset)
+		// $4015 is the only R/W register. Reading write only registers
+		// returns the last value on the data bus, which is often $40 as
+		// they are read with absolute addressing. The same goes for the
+		// disabled test registers at $4018-$401F, and for $4020-$5FFF which
+		// is left to cartridges that map nothing there.
+		return apu.openBus()
 	}
-	return 0
+}
+
+// Peek returns what MemRead would return, without acknowledging the frame
+// interrupt or reading the input devices (see memory.Peeker)
+func (apu *APU) Peek(offset uint16) byte {
+	if offset&0x1fff == 0x15 {
+		return apu.status() | apu.openBus()&0x20
+	}
+	return apu.openBus()
+}
+
+// openBus returns the last value on the CPU's data bus
+// See: https://www.nesdev.org/wiki/Open_bus_behavior
+func (apu *APU) openBus() byte {
+	return memory.OpenBus(apu.Memory)
 }
 
 func (apu *APU) MemWrite(offset uint16, val byte) byte {
@@ -74,17 +86,14 @@ func (apu *APU) MemWrite(offset uint16, val byte) byte {
 	case 0x14: // OAM DMA
 		// when writing to this port, send data of memory at HH=val to PPU's OAMDATA port
 		// https://www.nesdev.org/wiki/PPU_registers#OAM_DMA_($4014)_%3E_write
+		if apu.OAMDMA != nil {
+			apu.OAMDMA(val)
+			break
+		}
+		// no DMA unit, copy immediately
 		addr := uint16(val) << 8
 		for i := uint16(0); i < 256; i++ {
-			val = apu.Memory.MemRead(addr | i)
-			apu.Memory.MemWrite(0x2004, val)
-			apu.cpuDelay(2)
-		}
-		if apu.cpuDelay(0)&1 == 1 {
-			// odd cpu cycle
-			apu.cpuDelay(2)
-		} else {
-			apu.cpuDelay(1)
+			apu.Memory.MemWrite(0x2004, apu.Memory.MemRead(addr|i))
 		}
 	case 0x16: // controller polling mode
 		val = val & 7
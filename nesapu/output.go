@@ -26,6 +26,9 @@ func (apu *APU) fillBuffer() {
 }
 
 func (apu *APU) sendSample() {
+	if apu.NoAudio {
+		return
+	}
 	output := apu.filterChain.Step(apu.output())
 	select {
 	case apu.channel <- output:
@@ -0,0 +1,274 @@
+package nesapu
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// The structures below are the serialized form of the APU and its channels,
+// see SaveState. Audio filters are not saved.
+
+type apuState struct {
+	Cycle         uint64
+	FrameMode     byte
+	FrameValue    byte
+	FrameIRQ      bool
+	InterruptFlag bool
+
+	Pulse1, Pulse2 pulseState
+	Triangle       triangleState
+	Noise          noiseState
+	DMC            dmcState
+}
+
+type pulseState struct {
+	Enabled         bool
+	LengthEnabled   bool
+	LengthValue     byte
+	TimerPeriod     uint16
+	TimerValue      uint16
+	DutyMode        byte
+	DutyValue       byte
+	SweepReload     bool
+	SweepEnabled    bool
+	SweepNegate     bool
+	SweepShift      byte
+	SweepPeriod     byte
+	SweepValue      byte
+	EnvelopeEnabled bool
+	EnvelopeLoop    bool
+	EnvelopeStart   bool
+	EnvelopePeriod  byte
+	EnvelopeValue   byte
+	EnvelopeVolume  byte
+	ConstantVolume  byte
+}
+
+type triangleState struct {
+	Enabled       bool
+	LengthEnabled bool
+	LengthValue   byte
+	TimerPeriod   uint16
+	TimerValue    uint16
+	DutyValue     byte
+	CounterPeriod byte
+	CounterValue  byte
+	CounterReload bool
+}
+
+type noiseState struct {
+	Enabled         bool
+	Mode            bool
+	ShiftRegister   uint16
+	LengthEnabled   bool
+	LengthValue     byte
+	TimerPeriod     uint16
+	TimerValue      uint16
+	EnvelopeEnabled bool
+	EnvelopeLoop    bool
+	EnvelopeStart   bool
+	EnvelopePeriod  byte
+	EnvelopeValue   byte
+	EnvelopeVolume  byte
+	ConstantVolume  byte
+}
+
+type dmcState struct {
+	Enabled        bool
+	Value          byte
+	SampleAddress  uint16
+	SampleLength   uint16
+	CurrentAddress uint16
+	CurrentLength  uint16
+	ShiftRegister  byte
+	BitCount       byte
+	TickPeriod     byte
+	TickValue      byte
+	Loop           bool
+	IRQ            bool
+	IRQFlag        bool
+	Fetching       bool
+}
+
+// SaveState writes the state of the APU and all its channels to w
+func (apu *APU) SaveState(w io.Writer) error {
+	st := &apuState{
+		Cycle:         apu.cycle,
+		FrameMode:     apu.frameMode,
+		FrameValue:    apu.frameValue,
+		FrameIRQ:      apu.frameIRQ,
+		InterruptFlag: apu.interruptFlag,
+		Pulse1:        apu.pulse1.state(),
+		Pulse2:        apu.pulse2.state(),
+		Triangle:      apu.triangle.state(),
+		Noise:         apu.noise.state(),
+		DMC:           apu.dmc.state(),
+	}
+	return binary.Write(w, binary.LittleEndian, st)
+}
+
+// LoadState restores a state written by SaveState
+func (apu *APU) LoadState(r io.Reader) error {
+	var st apuState
+	if err := binary.Read(r, binary.LittleEndian, &st); err != nil {
+		return err
+	}
+
+	apu.cycle = st.Cycle
+	apu.frameMode = st.FrameMode
+	apu.frameValue = st.FrameValue
+	apu.frameIRQ = st.FrameIRQ
+	apu.interruptFlag = st.InterruptFlag
+	apu.pulse1.setState(&st.Pulse1)
+	apu.pulse2.setState(&st.Pulse2)
+	apu.triangle.setState(&st.Triangle)
+	apu.noise.setState(&st.Noise)
+	apu.dmc.setState(&st.DMC)
+	return nil
+}
+
+func (p *Pulse) state() pulseState {
+	return pulseState{
+		Enabled:         p.enabled,
+		LengthEnabled:   p.lengthEnabled,
+		LengthValue:     p.lengthValue,
+		TimerPeriod:     p.timerPeriod,
+		TimerValue:      p.timerValue,
+		DutyMode:        p.dutyMode,
+		DutyValue:       p.dutyValue,
+		SweepReload:     p.sweepReload,
+		SweepEnabled:    p.sweepEnabled,
+		SweepNegate:     p.sweepNegate,
+		SweepShift:      p.sweepShift,
+		SweepPeriod:     p.sweepPeriod,
+		SweepValue:      p.sweepValue,
+		EnvelopeEnabled: p.envelopeEnabled,
+		EnvelopeLoop:    p.envelopeLoop,
+		EnvelopeStart:   p.envelopeStart,
+		EnvelopePeriod:  p.envelopePeriod,
+		EnvelopeValue:   p.envelopeValue,
+		EnvelopeVolume:  p.envelopeVolume,
+		ConstantVolume:  p.constantVolume,
+	}
+}
+
+func (p *Pulse) setState(st *pulseState) {
+	p.enabled = st.Enabled
+	p.lengthEnabled = st.LengthEnabled
+	p.lengthValue = st.LengthValue
+	p.timerPeriod = st.TimerPeriod
+	p.timerValue = st.TimerValue
+	p.dutyMode = st.DutyMode
+	p.dutyValue = st.DutyValue
+	p.sweepReload = st.SweepReload
+	p.sweepEnabled = st.SweepEnabled
+	p.sweepNegate = st.SweepNegate
+	p.sweepShift = st.SweepShift
+	p.sweepPeriod = st.SweepPeriod
+	p.sweepValue = st.SweepValue
+	p.envelopeEnabled = st.EnvelopeEnabled
+	p.envelopeLoop = st.EnvelopeLoop
+	p.envelopeStart = st.EnvelopeStart
+	p.envelopePeriod = st.EnvelopePeriod
+	p.envelopeValue = st.EnvelopeValue
+	p.envelopeVolume = st.EnvelopeVolume
+	p.constantVolume = st.ConstantVolume
+}
+
+func (t *Triangle) state() triangleState {
+	return triangleState{
+		Enabled:       t.enabled,
+		LengthEnabled: t.lengthEnabled,
+		LengthValue:   t.lengthValue,
+		TimerPeriod:   t.timerPeriod,
+		TimerValue:    t.timerValue,
+		DutyValue:     t.dutyValue,
+		CounterPeriod: t.counterPeriod,
+		CounterValue:  t.counterValue,
+		CounterReload: t.counterReload,
+	}
+}
+
+func (t *Triangle) setState(st *triangleState) {
+	t.enabled = st.Enabled
+	t.lengthEnabled = st.LengthEnabled
+	t.lengthValue = st.LengthValue
+	t.timerPeriod = st.TimerPeriod
+	t.timerValue = st.TimerValue
+	t.dutyValue = st.DutyValue
+	t.counterPeriod = st.CounterPeriod
+	t.counterValue = st.CounterValue
+	t.counterReload = st.CounterReload
+}
+
+func (n *Noise) state() noiseState {
+	return noiseState{
+		Enabled:         n.enabled,
+		Mode:            n.mode,
+		ShiftRegister:   n.shiftRegister,
+		LengthEnabled:   n.lengthEnabled,
+		LengthValue:     n.lengthValue,
+		TimerPeriod:     n.timerPeriod,
+		TimerValue:      n.timerValue,
+		EnvelopeEnabled: n.envelopeEnabled,
+		EnvelopeLoop:    n.envelopeLoop,
+		EnvelopeStart:   n.envelopeStart,
+		EnvelopePeriod:  n.envelopePeriod,
+		EnvelopeValue:   n.envelopeValue,
+		EnvelopeVolume:  n.envelopeVolume,
+		ConstantVolume:  n.constantVolume,
+	}
+}
+
+func (n *Noise) setState(st *noiseState) {
+	n.enabled = st.Enabled
+	n.mode = st.Mode
+	n.shiftRegister = st.ShiftRegister
+	n.lengthEnabled = st.LengthEnabled
+	n.lengthValue = st.LengthValue
+	n.timerPeriod = st.TimerPeriod
+	n.timerValue = st.TimerValue
+	n.envelopeEnabled = st.EnvelopeEnabled
+	n.envelopeLoop = st.EnvelopeLoop
+	n.envelopeStart = st.EnvelopeStart
+	n.envelopePeriod = st.EnvelopePeriod
+	n.envelopeValue = st.EnvelopeValue
+	n.envelopeVolume = st.EnvelopeVolume
+	n.constantVolume = st.ConstantVolume
+}
+
+func (d *DMC) state() dmcState {
+	return dmcState{
+		Enabled:        d.enabled,
+		Value:          d.value,
+		SampleAddress:  d.sampleAddress,
+		SampleLength:   d.sampleLength,
+		CurrentAddress: d.currentAddress,
+		CurrentLength:  d.currentLength,
+		ShiftRegister:  d.shiftRegister,
+		BitCount:       d.bitCount,
+		TickPeriod:     d.tickPeriod,
+		TickValue:      d.tickValue,
+		Loop:           d.loop,
+		IRQ:            d.irq,
+		IRQFlag:        d.irqFlag,
+		Fetching:       d.fetching,
+	}
+}
+
+func (d *DMC) setState(st *dmcState) {
+	d.enabled = st.Enabled
+	d.value = st.Value
+	d.sampleAddress = st.SampleAddress
+	d.sampleLength = st.SampleLength
+	d.currentAddress = st.CurrentAddress
+	d.currentLength = st.CurrentLength
+	d.shiftRegister = st.ShiftRegister
+	d.bitCount = st.BitCount
+	d.tickPeriod = st.TickPeriod
+	d.tickValue = st.TickValue
+	d.loop = st.Loop
+	d.irq = st.IRQ
+	d.irqFlag = st.IRQFlag
+	d.fetching = st.Fetching
+}
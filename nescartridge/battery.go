@@ -0,0 +1,165 @@
+package nescartridge
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MagicalTux/gones/clock"
+	"github.com/MagicalTux/gones/memory"
+)
+
+// autosaveInterval is how often battery backed RAM is written to disk (if
+// it changed) while the emulator is running
+const autosaveInterval = 10 * time.Second
+
+// battery persists battery backed PRG RAM to a .sav file stored next to the
+// ROM file, so games can keep their save data across runs. The RAM is only
+// read on the emulation goroutine: a clock listener copies it to snapshot,
+// which is written to disk by the saver goroutine.
+type battery struct {
+	fn       string
+	ram      memory.RAM
+	snapshot []byte // copy of ram taken by the clock listener
+	saved    []byte // what is currently on disk
+	mu       sync.Mutex
+	started  bool // clock listener added by start
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// savePath returns the path of the .sav file for this cartridge
+func (d *Data) savePath() string {
+	return strings.TrimSuffix(d.path, filepath.Ext(d.path)) + ".sav"
+}
+
+// load reads the save file into ram if it exists
+func (b *battery) load(fn string, ram memory.RAM) error {
+	buf, err := os.ReadFile(fn)
+	if err != nil && !os.IsNotExist(err) {
+		// do not risk overwriting a file we couldn't read
+		return err
+	}
+	if len(buf) > 0 {
+		if len(buf) != len(ram) {
+			log.Printf("Battery: %s is %d bytes, expected %d bytes", fn, len(buf), len(ram))
+		}
+		copy(ram, buf)
+		log.Printf("Battery: loaded PRG RAM from %s", fn)
+	}
+
+	b.fn = fn
+	b.ram = ram
+	b.saved = make([]byte, len(ram))
+	copy(b.saved, ram)
+	b.snapshot = make([]byte, len(ram))
+	copy(b.snapshot, ram)
+
+	return nil
+}
+
+// start takes a snapshot of the RAM every emulated second on clk, and starts
+// saving it periodically. The clock listener is added even if no RAM was
+// loaded, as save states can only be loaded by machines with the same
+// listeners. It does nothing if saving was already started.
+func (b *battery) start(clk *clock.Master) {
+	if b.started {
+		return
+	}
+	b.started = true
+	clk.Listen(clk.Frequency(), 1, b.snap)
+
+	if b.ram == nil {
+		return
+	}
+	b.stop = make(chan struct{})
+	b.done = make(chan struct{})
+	go b.thread()
+}
+
+// snap is the clock listener copying the RAM to the snapshot
+func (b *battery) snap(uint64) uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	copy(b.snapshot, b.ram)
+	return 1
+}
+
+func (b *battery) thread() {
+	defer close(b.done)
+
+	t := time.NewTicker(autosaveInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-t.C:
+			if err := b.save(); err != nil {
+				log.Printf("Battery: failed to save: %s", err)
+			}
+		case <-b.stop:
+			return
+		}
+	}
+}
+
+// save writes the last snapshot of the RAM to disk if it changed since the
+// last save. The file is written to a temporary file first, then renamed so a
+// crash while saving cannot corrupt an existing save.
+func (b *battery) save() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.ram == nil {
+		return nil
+	}
+
+	buf := make([]byte, len(b.snapshot))
+	copy(buf, b.snapshot)
+	if bytes.Equal(buf, b.saved) {
+		return nil
+	}
+
+	tmp := b.fn + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(buf)
+	if err == nil {
+		err = f.Sync()
+	}
+	if err2 := f.Close(); err == nil {
+		err = err2
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("while writing %s: %w", tmp, err)
+	}
+	if err = os.Rename(tmp, b.fn); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	b.saved = buf
+	return nil
+}
+
+// close stops the periodic save and saves the current RAM one last time. The
+// emulation must be stopped (see clock.Master.Pause).
+func (b *battery) close() error {
+	if b.stop != nil {
+		close(b.stop)
+		<-b.done
+		b.stop = nil
+	}
+	b.mu.Lock()
+	copy(b.snapshot, b.ram)
+	b.mu.Unlock()
+	return b.save()
+}
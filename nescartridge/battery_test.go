@@ -0,0 +1,138 @@
+package nescartridge
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/MagicalTux/gones/pkgnes"
+)
+
+func TestBattery(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		save bool
+	}{
+		{"disabled", nil, false},
+		{"enabled", []Option{WithBattery()}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fn := filepath.Join(t.TempDir(), "game.nes")
+			if err := os.WriteFile(fn, testImage([12]byte{1, 1, flgBatteryRAM}, 0x4000, 0x2000), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			d, err := Load(fn, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			nes := pkgnes.New(d.Model())
+			if err := d.Setup(nes); err != nil {
+				t.Fatal(err)
+			}
+			nes.Memory.MemWrite(0x6000, 0x42)
+			if err := d.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			buf, err := os.ReadFile(filepath.Join(filepath.Dir(fn), "game.sav"))
+			if !tt.save {
+				if !os.IsNotExist(err) {
+					t.Errorf("save file written while persistence is disabled (err=%v)", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(buf) != 0x2000 || buf[0] != 0x42 || !bytes.Equal(buf[1:], make([]byte, 0x1fff)) {
+				t.Errorf("unexpected save file content")
+			}
+
+			// and it is loaded back
+			d, err = Load(fn, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer d.Close()
+			nes = pkgnes.New(d.Model())
+			if err := d.Setup(nes); err != nil {
+				t.Fatal(err)
+			}
+			if v := nes.Memory.MemRead(0x6000); v != 0x42 {
+				t.Errorf("read $%02x at $6000 after reloading, want $42", v)
+			}
+		})
+	}
+}
+
+func TestBatteryNVRAM(t *testing.T) {
+	fn := filepath.Join(t.TempDir(), "game.nes")
+	// NES 2.0 MMC1 with 8 KB of RAM followed by 8 KB of battery backed RAM
+	if err := os.WriteFile(fn, testImage([12]byte{2, 1, 0x10 | flgBatteryRAM, 0x08, 0, 0, 0x77}, 0x8000, 0x2000), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := Load(fn, WithBattery())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Setup(pkgnes.New(d.Model())); err != nil {
+		t.Fatal(err)
+	}
+	d.prgRAM[0x0000] = 0x11
+	d.prgRAM[0x2000] = 0x22
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	buf, err := os.ReadFile(filepath.Join(filepath.Dir(fn), "game.sav"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(buf) != 0x2000 || buf[0] != 0x22 {
+		t.Errorf("save file has %d bytes starting with $%02x, want the 8 KB of NVRAM starting with $22", len(buf), buf[0])
+	}
+}
+
+func TestBatteryState(t *testing.T) {
+	fn := filepath.Join(t.TempDir(), "game.nes")
+	if err := os.WriteFile(fn, testImage([12]byte{1, 1, flgBatteryRAM}, 0x4000, 0x2000), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// states saved with battery saving must load without it, and the reverse
+	machine := func(opts ...Option) *pkgnes.NES {
+		d, err := Load(fn, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { d.Close() })
+		nes := pkgnes.New(d.Model())
+		if err := d.Setup(nes); err != nil {
+			t.Fatal(err)
+		}
+		return nes
+	}
+	saving, headless := machine(WithBattery()), machine()
+
+	var st bytes.Buffer
+	if err := saving.SaveState(&st); err != nil {
+		t.Fatal(err)
+	}
+	if err := headless.LoadState(bytes.NewReader(st.Bytes())); err != nil {
+		t.Errorf("loading a state saved with battery saving: %s", err)
+	}
+
+	st.Reset()
+	if err := headless.SaveState(&st); err != nil {
+		t.Fatal(err)
+	}
+	if err := saving.LoadState(bytes.NewReader(st.Bytes())); err != nil {
+		t.Errorf("loading a state saved without battery saving: %s", err)
+	}
+}
@@ -1,6 +1,7 @@
 package nescartridge
 
 import (
+	"log"
 	"os"
 
 	"github.com/MagicalTux/gones/memory"
@@ -11,18 +12,52 @@ import (
 type Data struct {
 	f      *os.File
 	m      []byte // map+len
+	path   string // file the data was loaded from
 	Mapper Mapper
 
-	numPRG          byte // Size of PRG ROM in 16 KB units
-	numCHR          byte
-	numPRGram       int
+	SubMapper byte            // NES 2.0 submapper, 0 if not specified
+	Timing    TimingMode      // CPU/PPU timing
+	Console   ConsoleType     // console this was made for
+	Expansion ExpansionDevice // default expansion device (NES 2.0 only)
+
+	prgSize         int // Size of PRG ROM in bytes
+	chrSize         int // Size of CHR ROM in bytes, zero if the board uses CHR RAM
+	prgRAMSize      int // Size of volatile PRG RAM in bytes
+	prgNVRAMSize    int // Size of non-volatile (battery backed) PRG RAM in bytes
+	chrRAMSize      int // Size of volatile CHR RAM in bytes
+	chrNVRAMSize    int // Size of non-volatile CHR RAM in bytes
 	mapperType      MapperType
+	nes2            bool
 	hasTrainer      bool
+	hasBattery      bool
 	hasMirroring    bool
 	ignoreMirroring bool
+
+	battery     battery // battery backed PRG RAM persistence
+	saveBattery bool    // battery backed PRG RAM is loaded from and saved to disk, see WithBattery
+
+	prgRAM memory.RAM // PRG RAM, if any (see newPRGRAM)
+	chrRAM memory.RAM // CHR RAM, if any (see CHR)
+}
+
+// Option is an option of Load
+type Option func(d *Data)
+
+// WithBattery makes battery backed PRG RAM persistent: it is loaded from a
+// .sav file next to the ROM file, and saved to it periodically while the
+// emulation runs, and on Close.
+func WithBattery() Option {
+	return func(d *Data) {
+		d.saveBattery = true
+	}
 }
 
+// Close releases the cartridge, saving battery backed RAM first if enabled
+// (see WithBattery). The emulation must be stopped.
 func (d *Data) Close() error {
+	if err := d.battery.close(); err != nil {
+		log.Printf("Failed to save battery backed RAM: %s", err)
+	}
 	if d.m != nil {
 		d.unload()
 	}
@@ -34,44 +69,97 @@ func (d *Data) Close() error {
 	return nil
 }
 
-func (d *Data) PRG() []byte {
-	// get PRG data
+// Model returns the NES model this cartridge should be run on
+func (d *Data) Model() pkgnes.Model {
+	return d.Timing.Model()
+}
+
+func (d *Data) prgOffset() int {
 	// see: https://www.nesdev.org/wiki/INES#iNES_file_format
 	offt := 16
 	if d.hasTrainer {
 		offt += 512
 	}
+	return offt
+}
+
+// Trainer returns the 512 bytes trainer found in the file, or nil if there
+// is none. The trainer is loaded at $7000-$71FF when the cartridge is set up.
+func (d *Data) Trainer() []byte {
+	if !d.hasTrainer {
+		return nil
+	}
+	return d.m[16 : 16+512]
+}
 
-	romSize := int(d.numPRG) << 14 // 1=16kB 2=32kB etc...
+func (d *Data) PRG() []byte {
+	// get PRG data
+	offt := d.prgOffset()
 
-	return d.m[offt : offt+romSize]
+	return d.m[offt : offt+d.prgSize]
 }
 
 func (d *Data) CHR() memory.Handler {
-	if d.numCHR == 0 {
-		// 5: Size of CHR ROM in 8 KB units (Value 0 means the board uses CHR RAM)
-		return memory.NewRAM(0x2000)
+	if d.chrSize == 0 {
+		// The board uses CHR RAM
+		siz := d.chrRAMSize + d.chrNVRAMSize
+		if siz == 0 {
+			// no CHR at all in header, give 8kB of CHR RAM
+			siz = 0x2000
+		}
+		d.chrRAM = memory.NewRAM(ramSize(siz))
+		return d.chrRAM
 	}
 
 	// get CHR data
-	offt := 16
-	if d.hasTrainer {
-		offt += 512
-	}
+	offt := d.prgOffset() + d.prgSize
 
-	romSize := int(d.numPRG) << 14 // 1=16kB 2=32kB etc...
-	offt += romSize
+	return memory.ROM(d.m[offt : offt+d.chrSize])
+}
 
-	romSize = int(d.numCHR) << 13 // 1=8kB, ...
+// ramSize rounds siz up to a power of two, as memory.RAM masks offsets with
+// its length. NES 2.0 headers can declare both volatile and battery backed RAM,
+// such as 8 KB + 2 KB, whose sum isn't one.
+func ramSize(siz int) int {
+	n := 1
+	for n < siz {
+		n <<= 1
+	}
+	return n
+}
 
-	return memory.ROM(d.m[offt : offt+romSize])
+// newPRGRAM returns the PRG RAM for this cartridge, as declared in the
+// header. nil is returned if the cartridge has no PRG RAM. If the cartridge
+// has a battery and WithBattery was given, the battery backed RAM, which
+// follows the volatile RAM, is loaded from a .sav file (and saved to it once
+// Setup is done).
+func (d *Data) newPRGRAM() memory.RAM {
+	// smaller RAM chips are mirrored to fill the whole 8kB window (memory.RAM does this), mappers with
+	// more than 8kB of RAM need to do their own banking
+	siz := d.prgRAMSize + d.prgNVRAMSize
+	if siz == 0 {
+		return nil
+	}
+	ram := memory.NewRAM(ramSize(siz))
+	if d.hasBattery && d.saveBattery && d.prgNVRAMSize > 0 {
+		// only the battery backed part, which follows the volatile RAM, is saved
+		nv := ram[d.prgRAMSize : d.prgRAMSize+d.prgNVRAMSize]
+		if err := d.battery.load(d.savePath(), nv); err != nil {
+			log.Printf("Failed to load battery backed RAM: %s", err)
+		}
+	}
+	d.prgRAM = ram
+	return ram
 }
 
 func (d *Data) Setup(nes *pkgnes.NES) error {
-	err := d.Mapper.setup(nes)
-	if err != nil {
-		return err
+	if d.Console != ConsoleNES {
+		log.Printf("WARNING: cartridge was made for %s, it may not work as expected", d.Console)
 	}
+	if d.Timing != TimingMulti && d.Timing.Model() != nes.Model() {
+		log.Printf("WARNING: cartridge expects %s timing, but NES is not running with this timing", d.Timing)
+	}
+
 	// see https://www.nesdev.org/wiki/Mirroring#Nametable_Mirroring
 	if d.ignoreMirroring {
 		// Ignore mirroring control or above mirroring bit; instead provide four-screen VRAM
@@ -83,5 +171,20 @@ func (d *Data) Setup(nes *pkgnes.NES) error {
 		// 0: horizontal (vertical arrangement) (CIRAM A10 = PPU A11)
 		nes.PPU.SetMirroring(nesppu.HorizontalMirroring)
 	}
+
+	// mappers controlling mirroring can override the header's value during setup
+	err := d.Mapper.setup(nes)
+	if err != nil {
+		return err
+	}
+	nes.Cartridge = d
+	d.battery.start(nes.Clk)
+
+	if trainer := d.Trainer(); trainer != nil {
+		// copy trainer to PRG RAM at $7000-$71FF
+		for i, v := range trainer {
+			nes.Memory.MemWrite(0x7000+uint16(i), v)
+		}
+	}
 	return nil
 }
@@ -0,0 +1,88 @@
+package nescartridge
+
+import (
+	"fmt"
+
+	"github.com/MagicalTux/gones/pkgnes"
+)
+
+// TimingMode is the CPU/PPU timing expected by a cartridge
+type TimingMode byte
+
+// See: https://www.nesdev.org/wiki/NES_2.0#CPU/PPU_Timing
+const (
+	TimingNTSC  TimingMode = iota // RP2C02 ("NTSC NES")
+	TimingPAL                     // RP2C07 ("Licensed PAL NES")
+	TimingMulti                   // Multiple-region
+	TimingDendy                   // UMC 6527P ("Dendy")
+)
+
+func (t TimingMode) String() string {
+	switch t {
+	case TimingNTSC:
+		return "NTSC"
+	case TimingPAL:
+		return "PAL"
+	case TimingMulti:
+		return "Multi-region"
+	case TimingDendy:
+		return "Dendy"
+	default:
+		return fmt.Sprintf("TimingMode(%d)", t)
+	}
+}
+
+// Model returns the NES model best suited to run a cartridge with this timing
+func (t TimingMode) Model() pkgnes.Model {
+	switch t {
+	case TimingPAL, TimingDendy:
+		// Dendy has its own timings, but is closer to PAL than NTSC
+		return pkgnes.PAL
+	default:
+		return pkgnes.NTSC
+	}
+}
+
+// ConsoleType is the type of console a cartridge was made for
+type ConsoleType byte
+
+// See: https://www.nesdev.org/wiki/NES_2.0#Extended_Console_Type
+const (
+	ConsoleNES        ConsoleType = iota // Nintendo Entertainment System/Family Computer
+	ConsoleVsSystem                      // Nintendo Vs. System
+	ConsolePlayChoice                    // Nintendo Playchoice 10
+	ConsoleExtended                      // Extended Console Type, replaced by the actual value when parsing
+)
+
+func (c ConsoleType) String() string {
+	switch c {
+	case ConsoleNES:
+		return "NES"
+	case ConsoleVsSystem:
+		return "Vs. System"
+	case ConsolePlayChoice:
+		return "Playchoice 10"
+	default:
+		return fmt.Sprintf("ConsoleType(%d)", c)
+	}
+}
+
+// ExpansionDevice is the default expansion device a cartridge expects to be
+// connected, as found in NES 2.0 headers.
+type ExpansionDevice byte
+
+// See: https://www.nesdev.org/wiki/NES_2.0#Default_Expansion_Device
+const (
+	ExpansionUnspecified   ExpansionDevice = 0x00
+	ExpansionStandard      ExpansionDevice = 0x01 // Standard NES/Famicom controllers
+	ExpansionFourScore     ExpansionDevice = 0x02 // NES Four Score/Satellite with two additional standard controllers
+	ExpansionFamicom4P     ExpansionDevice = 0x03 // Famicom Four Players Adapter with two additional standard controllers
+	ExpansionVsSystem      ExpansionDevice = 0x04 // Vs. System (1P via $4016)
+	ExpansionVsSystem4017  ExpansionDevice = 0x05 // Vs. System (1P via $4017)
+	ExpansionVsZapper      ExpansionDevice = 0x07 // Vs. Zapper
+	ExpansionZapper        ExpansionDevice = 0x08 // Zapper ($4017)
+	ExpansionTwoZappers    ExpansionDevice = 0x09 // Two Zappers
+	ExpansionBandaiHyper   ExpansionDevice = 0x0a // Bandai Hyper Shot Lightgun
+	ExpansionPowerPadSideA ExpansionDevice = 0x0b // Power Pad Side A
+	ExpansionPowerPadSideB ExpansionDevice = 0x0c // Power Pad Side B
+)
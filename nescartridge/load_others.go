@@ -4,13 +4,19 @@ package nescartridge
 
 import "io/ioutil"
 
-func Load(fn string) (*Data, error) {
+// Load loads the cartridge in the iNES or NES 2.0 file fn
+func Load(fn string, opts ...Option) (*Data, error) {
 	mem, err := ioutil.ReadFile(fn)
 	if err != nil {
 		return nil, err
 	}
 	res := &Data{
-		m: mem,
+		m:    mem,
+		path: fn,
+	}
+
+	for _, opt := range opts {
+		opt(res)
 	}
 
 	if err = res.parse(); err != nil {
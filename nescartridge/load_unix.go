@@ -8,7 +8,8 @@ import (
 	"golang.org/x/sys/unix"
 )
 
-func Load(fn string) (*Data, error) {
+// Load loads the cartridge in the iNES or NES 2.0 file fn
+func Load(fn string, opts ...Option) (*Data, error) {
 	f, err := os.Open(fn)
 	if err != nil {
 		return nil, err
@@ -27,7 +28,8 @@ func Load(fn string) (*Data, error) {
 	}
 
 	res := &Data{
-		f: f,
+		f:    f,
+		path: fn,
 	}
 
 	err2 := sc.Control(func(fd uintptr) {
@@ -42,6 +44,10 @@ func Load(fn string) (*Data, error) {
 
 	log.Printf("Mapped %d bytes in memory", ln)
 
+	for _, opt := range opts {
+		opt(res)
+	}
+
 	if err = res.parse(); err != nil {
 		res.Close()
 		return nil, err
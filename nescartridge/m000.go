@@ -22,8 +22,10 @@ type MapperNROM struct {
 
 func (m *MapperNROM) setup(nes *pkgnes.NES) error {
 	// CPU $6000-$7FFF: Family Basic only: PRG RAM, mirrored as necessary to fill entire 8 KiB window, write protectable with an external switch
-	// we ignore numPRGram since value 0 means a 8kB RAM, value 1 means a 8kB ram, and higher values can't be addressed
-	nes.Memory.MapHandler(0x6000, 0x2000, memory.NewRAM(0x2000))
+	// iNES files always get 8kB here since their header can't be trusted, NES 2.0 files will get what they declare
+	if ram := m.data.newPRGRAM(); ram != nil {
+		nes.Memory.MapHandler(0x6000, 0x2000, ram)
+	}
 
 	// CPU $8000-$BFFF: First 16 KB of ROM.
 	// CPU $C000-$FFFF: Last 16 KB of ROM (NROM-256) or mirror of $8000-$BFFF (NROM-128).
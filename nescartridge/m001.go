@@ -1,6 +1,8 @@
 package nescartridge
 
 import (
+	"encoding/binary"
+	"io"
 	"log"
 	"os"
 	"unsafe"
@@ -24,6 +26,7 @@ func init() {
 type MMC1 struct {
 	data *Data
 	ppu  *nesppu.PPU
+	bus  memory.Master // CPU bus, for open bus reads
 
 	in byte
 
@@ -62,10 +65,12 @@ func (m *MMC1) setup(nes *pkgnes.NES) error {
 	nes.PPU.Memory.MapHandler(0x0000, 0x2000, m)
 
 	m.ppu = nes.PPU
+	m.bus = nes.Memory
 
-	// CPU $6000-$7FFF: Family Basic only: PRG RAM, mirrored as necessary to fill entire 8 KiB window, write protectable with an external switch
-	// we ignore numPRGram since value 0 means a 8kB RAM, value 1 means a 8kB ram, and higher values can't be addressed
-	m.prgRAM = memory.NewRAM(0x2000)
+	// CPU $6000-$7FFF: 8 KB PRG RAM bank, (optional)
+	if ram := m.data.newPRGRAM(); ram != nil {
+		m.prgRAM = ram
+	}
 	//m.prgRAM = &debugWrite{memory.NewRAM(0x2000)}
 
 	// 2022/10/02 16:02:59 Parsed iNes1 file, 8*16kB PRG, 0*8kB CHR, 1*8kB PRG RAM, mapper=1, trainer=false mirroring=true/false
@@ -95,11 +100,11 @@ func (m *MMC1) MemRead(offset uint16) byte {
 		}
 		return 0
 	case 6, 7:
-		// PRG RAM bank
+		// PRG RAM bank, nothing drives the bus without it
 		if m.prgRAM == nil {
-			return 0
+			return memory.OpenBus(m.bus)
 		}
-		return m.prgRAM.MemRead(offset)
+		return m.prgRAM.MemRead(m.prgRAMAddr(offset))
 	case 8, 9, 0xa, 0xb:
 		// 16 KB PRG ROM bank, either switchable or fixed to the first bank
 		if m.prgBank0 != nil {
@@ -141,7 +146,7 @@ func (m *MMC1) MemWrite(offset uint16, v byte) byte {
 		if m.prgRAM == nil {
 			return 0
 		}
-		return m.prgRAM.MemWrite(offset, v)
+		return m.prgRAM.MemWrite(m.prgRAMAddr(offset), v)
 	}
 
 	// we're writing to offset after 0x8000
@@ -210,6 +215,14 @@ func (m *MMC1) MemWrite(offset uint16, v byte) byte {
 	return 0
 }
 
+// prgRAMAddr returns the address in PRG RAM for a CPU access at offset. Boards
+// with more than 8 KB of PRG RAM (SOROM, SXROM) select its 8 KB bank with bits
+// 3 (A13) and 2 (A14) of the CHR bank 0 register, smaller RAMs ignore them.
+func (m *MMC1) prgRAMAddr(offset uint16) uint16 {
+	bank := uint16(m.chrBank0sel>>3&1 | m.chrBank0sel>>1&2)
+	return bank<<13 | offset&0x1fff
+}
+
 func (m *MMC1) updateBanks() {
 	switch m.prgMode {
 	case 0, 1:
@@ -247,6 +260,26 @@ func (m *MMC1) updateBanks() {
 	}
 }
 
+func (m *MMC1) saveState(w io.Writer) error {
+	st := [...]byte{m.in, m.prgMode, m.chrMode, m.chrBank0sel, m.chrBank1sel, m.prgBankSel}
+	return binary.Write(w, binary.LittleEndian, st)
+}
+
+func (m *MMC1) loadState(r io.Reader) error {
+	var st [6]byte
+	if err := binary.Read(r, binary.LittleEndian, &st); err != nil {
+		return err
+	}
+	m.in, m.prgMode, m.chrMode, m.chrBank0sel, m.chrBank1sel, m.prgBankSel = st[0], st[1], st[2], st[3], st[4], st[5]
+	// mirroring is part of the PPU's state
+	m.updateBanks()
+	return nil
+}
+
+func (m *MMC1) prgBankSize() int {
+	return 0x4000 // 16 KB, or 32 KB in one of the PRG modes
+}
+
 func (m *MMC1) Ptr() uintptr {
 	return uintptr(unsafe.Pointer(m))
 }
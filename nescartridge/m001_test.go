@@ -0,0 +1,57 @@
+package nescartridge
+
+import "testing"
+
+// mmc1Write writes v to an MMC1 register through its serial port
+func mmc1Write(d *Data, addr uint16, v byte) {
+	m := d.Mapper.(*MMC1)
+	for i := 0; i < 5; i++ {
+		m.MemWrite(addr, v>>i&1)
+	}
+}
+
+func TestMMC1PRGRAMBanks(t *testing.T) {
+	tests := []struct {
+		name  string
+		ram   byte   // NES 2.0 PRG RAM shift count
+		banks []byte // CHR bank 0 values selecting different PRG RAM banks
+	}{
+		{"8 KB", 7, []byte{0x00}},
+		{"16 KB (SOROM)", 8, []byte{0x00, 0x08}},
+		{"32 KB (SXROM)", 9, []byte{0x00, 0x08, 0x04, 0x0c}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, nes := testCart(t, testImage([12]byte{2, 0, 0x10, 0x08, 0, 0, tt.ram}, 0x8000, 0))
+
+			for n, sel := range tt.banks {
+				mmc1Write(d, 0xa000, sel)
+				nes.Memory.MemWrite(0x6123, byte(n+1))
+				nes.Memory.MemWrite(0x7fff, byte(n+1))
+			}
+			for n, sel := range tt.banks {
+				mmc1Write(d, 0xa000, sel)
+				if v := nes.Memory.MemRead(0x6123); v != byte(n+1) {
+					t.Errorf("bank %d: read $%02x at $6123, want $%02x", n, v, n+1)
+				}
+				if v := nes.Memory.MemRead(0x7fff); v != byte(n+1) {
+					t.Errorf("bank %d: read $%02x at $7fff, want $%02x", n, v, n+1)
+				}
+			}
+			if len(d.prgRAM) != 64<<tt.ram {
+				t.Errorf("PRG RAM is %d bytes, want %d", len(d.prgRAM), 64<<tt.ram)
+			}
+		})
+	}
+}
+
+func TestMMC1NoPRGRAM(t *testing.T) {
+	_, nes := testCart(t, testImage([12]byte{2, 0, 0x10, 0x08}, 0x8000, 0))
+
+	// nothing drives the bus, the last value seen on it is read
+	nes.Memory.MemWrite(0x0000, 0x5a)
+	if v := nes.Memory.MemRead(0x6000); v != 0x5a {
+		t.Errorf("read $%02x at $6000, want open bus $5a", v)
+	}
+}
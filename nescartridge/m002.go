@@ -0,0 +1,119 @@
+package nescartridge
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"unsafe"
+
+	"github.com/MagicalTux/gones/memory"
+	"github.com/MagicalTux/gones/pkgnes"
+)
+
+const (
+	UxROM MapperType = 0x02 // Nintendo cartridge boards NES-UNROM, NES-UOROM, HVC-UN1ROM their HVC counterparts, and clone boards
+)
+
+func init() {
+	RegisterMapper(UxROM, func(data *Data) Mapper {
+		return &MapperUxROM{
+			data: data,
+			// submapper 1 means no bus conflicts, 2 means bus conflicts, and unspecified boards typically have them
+			busConflicts: data.SubMapper != 1,
+		}
+	})
+}
+
+// https://www.nesdev.org/wiki/UxROM
+type MapperUxROM struct {
+	data *Data
+	mem  memory.Master
+
+	prg  memory.ROM
+	bank byte
+
+	busConflicts bool
+}
+
+func (m *MapperUxROM) setup(nes *pkgnes.NES) error {
+	m.mem = nes.Memory
+	m.prg = memory.ROM(m.data.PRG())
+	if len(m.prg) < 0x4000 {
+		return fmt.Errorf("UxROM needs at least 16 KB of PRG ROM, got %d bytes", len(m.prg))
+	}
+
+	// UxROM boards have no PRG RAM, but NES 2.0 headers may declare some
+	if ram := m.data.newPRGRAM(); ram != nil {
+		nes.Memory.MapHandler(0x6000, 0x2000, ram)
+	}
+
+	// PPU $0000-$1FFF: 8 KB CHR RAM (or ROM on some weird boards)
+	nes.PPU.Memory.MapHandler(0x0000, 0x2000, m.data.CHR())
+
+	m.updateBanks()
+
+	return nil
+}
+
+func (m *MapperUxROM) updateBanks() {
+	// CPU $8000-$BFFF: 16 KB switchable PRG ROM bank
+	// CPU $C000-$FFFF: 16 KB PRG ROM bank, fixed to the last bank
+	n := int(m.bank) % (len(m.prg) >> 14)
+	size := len(m.prg)
+	bank0 := memory.Slice(m.prg, 0x4000*n, 0x4000*n+0x4000)
+	bank1 := memory.Slice(m.prg, size-0x4000, size)
+
+	m.mem.ClearMapping(0x8000, 0x8000)
+	if m.busConflicts {
+		// mapping the ROM first means our register will see the AND of the ROM and the written value
+		m.mem.MapHandler(0x8000, 0x4000, bank0)
+		m.mem.MapHandler(0xc000, 0x4000, bank1)
+		m.mem.MapHandler(0x8000, 0x8000, m)
+	} else {
+		m.mem.MapHandler(0x8000, 0x8000, m)
+		m.mem.MapHandler(0x8000, 0x4000, bank0)
+		m.mem.MapHandler(0xc000, 0x4000, bank1)
+	}
+}
+
+func (m *MapperUxROM) MemRead(offset uint16) byte {
+	// reads are handled by the ROM
+	return 0
+}
+
+func (m *MapperUxROM) MemWrite(offset uint16, v byte) byte {
+	// Bank select ($8000-$FFFF)
+	if v != m.bank {
+		m.bank = v
+		m.updateBanks()
+	}
+	return 0
+}
+
+func (m *MapperUxROM) saveState(w io.Writer) error {
+	return binary.Write(w, binary.LittleEndian, m.bank)
+}
+
+func (m *MapperUxROM) loadState(r io.Reader) error {
+	if err := binary.Read(r, binary.LittleEndian, &m.bank); err != nil {
+		return err
+	}
+	m.updateBanks()
+	return nil
+}
+
+func (m *MapperUxROM) prgBankSize() int {
+	return 0x4000
+}
+
+func (m *MapperUxROM) Ptr() uintptr {
+	return uintptr(unsafe.Pointer(m))
+}
+
+func (m *MapperUxROM) String() string {
+	return "UxROM Mapper"
+}
+
+func (m *MapperUxROM) Length() uint16 {
+	return 0
+}
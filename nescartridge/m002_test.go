@@ -0,0 +1,54 @@
+package nescartridge
+
+import "testing"
+
+func TestUxROM(t *testing.T) {
+	// 128 KB PRG: 8 banks of 16 KB, each 1 KB of ROM contains its number
+	tests := []struct {
+		name      string
+		submapper byte
+		addr      uint16 // where the bank number is written, the ROM contains addr>>10&0x0f|0x70 there
+		v         byte
+		bank      int // bank mapped at $8000 after the write
+	}{
+		{"conflict", 2, 0xc000, 0x03, 0},     // $70 & $03
+		{"conflict AND", 2, 0xc400, 0x05, 1}, // $71 & $05
+		{"no conflict bits", 2, 0xfc00, 0x03, 3},
+		{"unspecified has conflicts", 0, 0xc000, 0x03, 0},
+		{"no conflicts", 1, 0xc000, 0x03, 3},
+		{"bank wraps", 1, 0xc000, 0x0d, 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, nes := testCart(t, testImage([12]byte{8, 0, 0x20, 0x08, tt.submapper << 4}, 0x20000, 0))
+
+			if v := nes.Memory.MemRead(0x8000); v != 0 {
+				t.Errorf("bank at $8000 after reset has $%02x, want bank 0", v)
+			}
+			nes.Memory.MemWrite(tt.addr, tt.v)
+			if v, want := nes.Memory.MemRead(0x8000), byte(tt.bank*16); v != want {
+				t.Errorf("read $%02x at $8000, want $%02x", v, want)
+			}
+			if v, want := nes.Memory.MemRead(0xbfff), byte(tt.bank*16+15); v != want {
+				t.Errorf("read $%02x at $bfff, want $%02x", v, want)
+			}
+			// last bank is fixed at $C000
+			if v := nes.Memory.MemRead(0xc000); v != 0x70 {
+				t.Errorf("read $%02x at $c000, want $70", v)
+			}
+			if v := nes.Memory.MemRead(0xffff); v != 0x7f {
+				t.Errorf("read $%02x at $ffff, want $7f", v)
+			}
+		})
+	}
+}
+
+func TestUxROMCHRRAM(t *testing.T) {
+	_, nes := testCart(t, testImage([12]byte{2, 0, 0x20}, 0x8000, 0))
+
+	nes.PPU.Memory.MemWrite(0x1234, 0x42)
+	if v := nes.PPU.Memory.MemRead(0x1234); v != 0x42 {
+		t.Errorf("read $%02x from CHR RAM, want $42", v)
+	}
+}
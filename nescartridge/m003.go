@@ -0,0 +1,112 @@
+package nescartridge
+
+import (
+	"encoding/binary"
+	"io"
+	"unsafe"
+
+	"github.com/MagicalTux/gones/memory"
+	"github.com/MagicalTux/gones/nesppu"
+	"github.com/MagicalTux/gones/pkgnes"
+)
+
+const (
+	CNROM MapperType = 0x03 // Nintendo cartridge boards NES-CNROM, HVC-CNROM, and clone boards
+)
+
+func init() {
+	RegisterMapper(CNROM, func(data *Data) Mapper {
+		return &MapperCNROM{
+			data: data,
+			// submapper 1 means no bus conflicts, 2 means bus conflicts, and unspecified boards typically have them
+			busConflicts: data.SubMapper != 1,
+		}
+	})
+}
+
+// https://www.nesdev.org/wiki/CNROM
+type MapperCNROM struct {
+	data *Data
+	ppu  *nesppu.PPU
+
+	chr      memory.Handler
+	chrBanks int
+	bank     byte
+
+	busConflicts bool
+}
+
+func (m *MapperCNROM) setup(nes *pkgnes.NES) error {
+	m.ppu = nes.PPU
+	m.chr = m.data.CHR()
+	m.chrBanks = m.data.chrSize >> 13
+	if m.chrBanks == 0 {
+		// CHR RAM
+		m.chrBanks = 1
+	}
+
+	if ram := m.data.newPRGRAM(); ram != nil {
+		nes.Memory.MapHandler(0x6000, 0x2000, ram)
+	}
+
+	// CPU $8000-$FFFF: 16 KB PRG ROM, or 32 KB mirrored
+	rom := memory.ROM(m.data.PRG())
+	if m.busConflicts {
+		// mapping the ROM first means our register will see the AND of the ROM and the written value
+		nes.Memory.MapHandler(0x8000, 0x8000, rom)
+		nes.Memory.MapHandler(0x8000, 0x8000, m)
+	} else {
+		nes.Memory.MapHandler(0x8000, 0x8000, m)
+		nes.Memory.MapHandler(0x8000, 0x8000, rom)
+	}
+
+	m.updateBanks()
+
+	return nil
+}
+
+func (m *MapperCNROM) updateBanks() {
+	// PPU $0000-$1FFF: 8 KB switchable CHR ROM bank
+	n := int(m.bank) % m.chrBanks
+
+	m.ppu.Memory.ClearMapping(0x0000, 0x2000)
+	m.ppu.Memory.MapHandler(0x0000, 0x2000, memory.Slice(m.chr, 0x2000*n, 0x2000*n+0x2000))
+}
+
+func (m *MapperCNROM) MemRead(offset uint16) byte {
+	// reads are handled by the ROM
+	return 0
+}
+
+func (m *MapperCNROM) MemWrite(offset uint16, v byte) byte {
+	// Bank select ($8000-$FFFF)
+	if v != m.bank {
+		m.bank = v
+		m.updateBanks()
+	}
+	return 0
+}
+
+func (m *MapperCNROM) saveState(w io.Writer) error {
+	return binary.Write(w, binary.LittleEndian, m.bank)
+}
+
+func (m *MapperCNROM) loadState(r io.Reader) error {
+	if err := binary.Read(r, binary.LittleEndian, &m.bank); err != nil {
+		return err
+	}
+	m.updateBanks()
+	return nil
+}
+
+func (m *MapperCNROM) Ptr() uintptr {
+	return uintptr(unsafe.Pointer(m))
+}
+
+func (m *MapperCNROM) String() string {
+	return "CNROM Mapper"
+}
+
+func (m *MapperCNROM) Length() uint16 {
+	return 0
+}
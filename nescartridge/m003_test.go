@@ -0,0 +1,64 @@
+package nescartridge
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCNROM(t *testing.T) {
+	// 32 KB PRG where each 1 KB contains its number, 32 KB CHR: 4 banks of 8 KB
+	tests := []struct {
+		name      string
+		submapper byte
+		addr      uint16
+		v         byte
+		bank      int // CHR bank mapped after the write
+	}{
+		{"conflict", 2, 0x8000, 0x03, 0},     // $00 & $03
+		{"conflict AND", 2, 0x8c00, 0x02, 2}, // $03 & $02
+		{"no conflict bits", 2, 0xfc00, 0x03, 3},
+		{"unspecified has conflicts", 0, 0x8400, 0x02, 0},
+		{"no conflicts", 1, 0x8000, 0x02, 2},
+		{"bank wraps", 1, 0x8000, 0x05, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, nes := testCart(t, testImage([12]byte{2, 4, 0x30, 0x08, tt.submapper << 4}, 0x8000, 0x8000))
+
+			if v := nes.PPU.Memory.MemRead(0x0000); v != 0 {
+				t.Errorf("CHR bank after reset has $%02x, want bank 0", v)
+			}
+			nes.Memory.MemWrite(tt.addr, tt.v)
+			if v, want := nes.PPU.Memory.MemRead(0x0000), byte(tt.bank*8); v != want {
+				t.Errorf("read $%02x at PPU $0000, want $%02x", v, want)
+			}
+			if v, want := nes.PPU.Memory.MemRead(0x1fff), byte(tt.bank*8+7); v != want {
+				t.Errorf("read $%02x at PPU $1fff, want $%02x", v, want)
+			}
+			// PRG is not switched
+			if v := nes.Memory.MemRead(0xfc00); v != 0x1f {
+				t.Errorf("read $%02x at $fc00, want $1f", v)
+			}
+		})
+	}
+}
+
+func TestCNROMState(t *testing.T) {
+	img := testImage([12]byte{2, 4, 0x30, 0x08, 0x10}, 0x8000, 0x8000)
+	d, nes := testCart(t, img)
+	nes.Memory.MemWrite(0x8000, 2)
+
+	var buf bytes.Buffer
+	if err := d.SaveState(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	d2, nes2 := testCart(t, img)
+	if err := d2.LoadState(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if v := nes2.PPU.Memory.MemRead(0x0000); v != 16 {
+		t.Errorf("read $%02x at PPU $0000 after loading state, want $10", v)
+	}
+}
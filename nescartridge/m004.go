@@ -0,0 +1,287 @@
+package nescartridge
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"unsafe"
+
+	"github.com/MagicalTux/gones/cpu6502"
+	"github.com/MagicalTux/gones/memory"
+	"github.com/MagicalTux/gones/nesppu"
+	"github.com/MagicalTux/gones/pkgnes"
+)
+
+const (
+	MMC3 MapperType = 0x04 // Nintendo cartridge boards TxROM (TSROM, TLROM, etc), HKROM (MMC6) and clones
+)
+
+func init() {
+	RegisterMapper(MMC3, func(data *Data) Mapper {
+		return &MapperMMC3{
+			data:          data,
+			prgRAMEnabled: true,
+		}
+	})
+}
+
+// https://www.nesdev.org/wiki/MMC3
+type MapperMMC3 struct {
+	data *Data
+	ppu  *nesppu.PPU
+	bus  memory.Master // CPU bus, for open bus reads
+	irq  func(bool)
+
+	prg    memory.ROM
+	chr    memory.Handler
+	chrCnt int // number of 1 KB CHR banks
+	prgRAM memory.Handler
+
+	bankSelect byte    // $8000: bank register to update on next write to $8001, and banking modes
+	regs       [8]byte // R0~R7
+
+	prgRAMEnabled      bool
+	prgRAMWriteProtect bool
+
+	irqLatch   byte
+	irqCounter byte
+	irqReload  bool
+	irqEnabled bool
+
+	prgBanks [4]memory.Handler // 8 KB banks at $8000, $A000, $C000 and $E000
+	chrBanks [8]memory.Handler // 1 KB banks from $0000 to $1FFF
+}
+
+func (m *MapperMMC3) setup(nes *pkgnes.NES) error {
+	if len(m.data.PRG()) < 0x2000 {
+		return fmt.Errorf("MMC3 needs at least 8 KB of PRG ROM, got %d bytes", len(m.data.PRG()))
+	}
+
+	nes.Memory.MapHandler(0x6000, 0x2000, m)
+	nes.Memory.MapHandler(0x8000, 0x8000, m)
+	nes.PPU.Memory.MapHandler(0x0000, 0x2000, m)
+
+	m.ppu = nes.PPU
+	m.bus = nes.Memory
+	m.irq = func(v bool) { nes.CPU.SetIRQ(cpu6502.IRQMapper, v) }
+	nes.PPU.A12Rising = m.clockScanline
+
+	// CPU $6000-$7FFF: 8 KB PRG RAM bank (optional), the MMC3 can't bank PRG RAM so only the first 8 KB of larger
+	// RAMs declared by NES 2.0 headers are used
+	if ram := m.data.newPRGRAM(); len(ram) > 0x2000 {
+		m.prgRAM = ram[:0x2000]
+	} else if ram != nil {
+		m.prgRAM = ram
+	}
+
+	m.prg = memory.ROM(m.data.PRG())
+	m.chr = m.data.CHR()
+	m.chrCnt = m.data.chrSize >> 10
+	if ram, ok := m.chr.(memory.RAM); ok {
+		m.chrCnt = len(ram) >> 10
+	}
+
+	m.updateBanks()
+
+	return nil
+}
+
+func (m *MapperMMC3) MemRead(offset uint16) byte {
+	switch offset >> 12 {
+	case 0, 1:
+		// PPU $0000-$1FFF: 1 KB CHR banks
+		if b := m.chrBanks[offset>>10]; b != nil {
+			return b.MemRead(offset)
+		}
+		return 0
+	case 6, 7:
+		// PRG RAM bank, nothing drives the bus when disabled
+		if m.prgRAM == nil || !m.prgRAMEnabled {
+			return memory.OpenBus(m.bus)
+		}
+		return m.prgRAM.MemRead(offset)
+	case 8, 9, 0xa, 0xb, 0xc, 0xd, 0xe, 0xf:
+		// CPU $8000-$FFFF: 8 KB PRG ROM banks
+		if b := m.prgBanks[(offset>>13)&3]; b != nil {
+			return b.MemRead(offset)
+		}
+		return 0
+	default:
+		return 0
+	}
+}
+
+func (m *MapperMMC3) MemWrite(offset uint16, v byte) byte {
+	switch offset >> 12 {
+	case 0, 1:
+		// PPU $0000-$1FFF: 1 KB CHR banks (only has effect with CHR RAM)
+		if b := m.chrBanks[offset>>10]; b != nil {
+			return b.MemWrite(offset, v)
+		}
+		return 0
+	case 6, 7:
+		// PRG RAM bank
+		if m.prgRAM == nil || !m.prgRAMEnabled || m.prgRAMWriteProtect {
+			return 0
+		}
+		return m.prgRAM.MemWrite(offset, v)
+	case 8, 9:
+		if offset&1 == 0 {
+			// Bank select ($8000-$9FFE, even)
+			m.bankSelect = v
+		} else {
+			// Bank data ($8001-$9FFF, odd)
+			m.regs[m.bankSelect&7] = v
+		}
+		m.updateBanks()
+	case 0xa, 0xb:
+		if offset&1 == 0 {
+			// Mirroring ($A000-$BFFE, even), no effect if the cartridge provides four screen VRAM
+			if m.data.ignoreMirroring {
+				return 0
+			}
+			if v&1 == 0 {
+				m.ppu.SetMirroring(nesppu.VerticalMirroring)
+			} else {
+				m.ppu.SetMirroring(nesppu.HorizontalMirroring)
+			}
+		} else {
+			// PRG RAM protect ($A001-$BFFF, odd)
+			m.prgRAMEnabled = v&0x80 == 0x80
+			m.prgRAMWriteProtect = v&0x40 == 0x40
+		}
+	case 0xc, 0xd:
+		if offset&1 == 0 {
+			// IRQ latch ($C000-$DFFE, even)
+			m.irqLatch = v
+		} else {
+			// IRQ reload ($C001-$DFFF, odd)
+			m.irqCounter = 0
+			m.irqReload = true
+		}
+	case 0xe, 0xf:
+		// IRQ disable ($E000-$FFFE, even) and IRQ enable ($E001-$FFFF, odd)
+		m.irqEnabled = offset&1 == 1
+		if !m.irqEnabled {
+			// disabling also acknowledges any pending interrupt
+			m.irq(false)
+		}
+	}
+	return 0
+}
+
+// clockScanline is called by the PPU on rising edges of A12, typically once
+// per scanline when rendering is enabled
+func (m *MapperMMC3) clockScanline() {
+	if m.irqCounter == 0 || m.irqReload {
+		m.irqCounter = m.irqLatch
+		m.irqReload = false
+	} else {
+		m.irqCounter--
+	}
+
+	if m.irqCounter == 0 && m.irqEnabled {
+		m.irq(true)
+	}
+}
+
+func (m *MapperMMC3) updateBanks() {
+	// PRG banks
+	// R6 and R7 select 8 KB banks, the second-last bank is fixed at either $8000 or $C000 depending on bit 6 of bank select
+	cnt := len(m.prg) >> 13
+	prg := func(n int) memory.Handler {
+		n %= cnt
+		return memory.Slice(m.prg, 0x2000*n, 0x2000*n+0x2000)
+	}
+
+	if m.bankSelect&0x40 == 0 {
+		m.prgBanks[0] = prg(int(m.regs[6]))
+		m.prgBanks[2] = prg(cnt - 2)
+	} else {
+		m.prgBanks[0] = prg(cnt - 2)
+		m.prgBanks[2] = prg(int(m.regs[6]))
+	}
+	m.prgBanks[1] = prg(int(m.regs[7]))
+	m.prgBanks[3] = prg(cnt - 1)
+
+	// CHR banks
+	// R0 and R1 select 2 KB banks (ignoring their low bit), R2~R5 select 1 KB banks. With bit 7 of bank select
+	// set, the 2 KB banks are at $1000-$1FFF instead of $0000-$0FFF (and 1 KB banks are moved accordingly)
+	chr := func(n int) memory.Handler {
+		n %= m.chrCnt
+		return memory.Slice(m.chr, 0x400*n, 0x400*n+0x400)
+	}
+
+	inv := 0
+	if m.bankSelect&0x80 == 0x80 {
+		inv = 4
+	}
+	m.chrBanks[0^inv] = chr(int(m.regs[0] & 0xfe))
+	m.chrBanks[1^inv] = chr(int(m.regs[0] | 1))
+	m.chrBanks[2^inv] = chr(int(m.regs[1] & 0xfe))
+	m.chrBanks[3^inv] = chr(int(m.regs[1] | 1))
+	m.chrBanks[4^inv] = chr(int(m.regs[2]))
+	m.chrBanks[5^inv] = chr(int(m.regs[3]))
+	m.chrBanks[6^inv] = chr(int(m.regs[4]))
+	m.chrBanks[7^inv] = chr(int(m.regs[5]))
+}
+
+// mmc3State is the serialized form of MapperMMC3's registers
+type mmc3State struct {
+	BankSelect         byte
+	Regs               [8]byte
+	PRGRAMEnabled      bool
+	PRGRAMWriteProtect bool
+	IRQLatch           byte
+	IRQCounter         byte
+	IRQReload          bool
+	IRQEnabled         bool
+}
+
+func (m *MapperMMC3) saveState(w io.Writer) error {
+	st := &mmc3State{
+		BankSelect:         m.bankSelect,
+		Regs:               m.regs,
+		PRGRAMEnabled:      m.prgRAMEnabled,
+		PRGRAMWriteProtect: m.prgRAMWriteProtect,
+		IRQLatch:           m.irqLatch,
+		IRQCounter:         m.irqCounter,
+		IRQReload:          m.irqReload,
+		IRQEnabled:         m.irqEnabled,
+	}
+	return binary.Write(w, binary.LittleEndian, st)
+}
+
+func (m *MapperMMC3) loadState(r io.Reader) error {
+	var st mmc3State
+	if err := binary.Read(r, binary.LittleEndian, &st); err != nil {
+		return err
+	}
+	m.bankSelect = st.BankSelect
+	m.regs = st.Regs
+	m.prgRAMEnabled = st.PRGRAMEnabled
+	m.prgRAMWriteProtect = st.PRGRAMWriteProtect
+	m.irqLatch = st.IRQLatch
+	m.irqCounter = st.IRQCounter
+	m.irqReload = st.IRQReload
+	m.irqEnabled = st.IRQEnabled
+	// mirroring is part of the PPU's state
+	m.updateBanks()
+	return nil
+}
+
+func (m *MapperMMC3) prgBankSize() int {
+	return 0x2000
+}
+
+func (m *MapperMMC3) Ptr() uintptr {
+	return uintptr(unsafe.Pointer(m))
+}
+
+func (m *MapperMMC3) String() string {
+	return "MMC3 Mapper"
+}
+
+func (m *MapperMMC3) Length() uint16 {
+	return 0
+}
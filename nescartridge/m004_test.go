@@ -0,0 +1,156 @@
+package nescartridge
+
+import (
+	"testing"
+
+	"github.com/MagicalTux/gones/cpu6502"
+	"github.com/MagicalTux/gones/pkgnes"
+)
+
+// mmc3Image is a MMC3 cartridge with 128 KB PRG (16 banks of 8 KB) and 128 KB
+// CHR, each 1 KB containing its number
+var mmc3Image = testImage([12]byte{8, 16, 0x40}, 0x20000, 0x20000)
+
+// mmc3Regs writes v to the bank registers R0 to R7
+func mmc3Regs(nes *pkgnes.NES, mode byte, v [8]byte) {
+	for n, b := range v {
+		nes.Memory.MemWrite(0x8000, mode|byte(n))
+		nes.Memory.MemWrite(0x8001, b)
+	}
+}
+
+func TestMMC3PRGBanks(t *testing.T) {
+	tests := []struct {
+		name string
+		mode byte
+		want [4]byte // 8 KB PRG bank at $8000, $A000, $C000 and $E000
+	}{
+		{"mode 0", 0x00, [4]byte{3, 5, 14, 15}},
+		{"mode 1", 0x40, [4]byte{14, 5, 3, 15}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, nes := testCart(t, mmc3Image)
+			mmc3Regs(nes, tt.mode, [8]byte{6: 3, 7: 0x15}) // R7 wraps to bank 5
+
+			for n, bank := range tt.want {
+				addr := 0x8000 + uint16(n)*0x2000
+				if v := nes.Memory.MemRead(addr); v != bank*8 {
+					t.Errorf("read $%02x at $%04x, want bank %d", v, addr, bank)
+				}
+				if v := nes.Memory.MemRead(addr + 0x1fff); v != bank*8+7 {
+					t.Errorf("read $%02x at $%04x, want bank %d", v, addr+0x1fff, bank)
+				}
+			}
+		})
+	}
+}
+
+func TestMMC3CHRBanks(t *testing.T) {
+	tests := []struct {
+		name string
+		mode byte
+		want [8]byte // 1 KB CHR bank from $0000 to $1C00
+	}{
+		{"mode 0", 0x00, [8]byte{4, 5, 8, 9, 20, 21, 22, 23}},
+		{"inverted", 0x80, [8]byte{20, 21, 22, 23, 4, 5, 8, 9}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, nes := testCart(t, mmc3Image)
+			// the low bit of R0 and R1 is ignored
+			mmc3Regs(nes, tt.mode, [8]byte{5, 8, 20, 21, 22, 23 + 128})
+
+			for n, bank := range tt.want {
+				addr := uint16(n) * 0x400
+				if v := nes.PPU.Memory.MemRead(addr); v != bank {
+					t.Errorf("read $%02x at PPU $%04x, want bank %d", v, addr, bank)
+				}
+			}
+		})
+	}
+}
+
+func TestMMC3IRQ(t *testing.T) {
+	tests := []struct {
+		name  string
+		latch byte
+		fire  []bool // IRQ asserted by each clock of the counter
+	}{
+		{"latch 3", 3, []bool{false, false, false, true, false, false, false, true}},
+		{"latch 0", 0, []bool{true, true, true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, nes := testCart(t, mmc3Image)
+			nes.Memory.MemWrite(0xc000, tt.latch)
+			nes.Memory.MemWrite(0xc001, 0)
+			nes.Memory.MemWrite(0xe001, 0)
+
+			for n, want := range tt.fire {
+				nes.PPU.A12Rising()
+				if got := nes.CPU.IRQ()&cpu6502.IRQMapper != 0; got != want {
+					t.Errorf("IRQ after clock %d is %v, want %v", n+1, got, want)
+				}
+				// acknowledge, and enable again
+				nes.Memory.MemWrite(0xe000, 0)
+				nes.Memory.MemWrite(0xe001, 0)
+			}
+		})
+	}
+}
+
+func TestMMC3IRQDisabled(t *testing.T) {
+	_, nes := testCart(t, mmc3Image)
+	nes.Memory.MemWrite(0xc000, 1)
+	nes.Memory.MemWrite(0xc001, 0)
+
+	for n := 0; n < 4; n++ {
+		nes.PPU.A12Rising()
+		if nes.CPU.IRQ()&cpu6502.IRQMapper != 0 {
+			t.Fatalf("IRQ asserted after clock %d while disabled", n+1)
+		}
+	}
+}
+
+func TestMMC3PRGRAM(t *testing.T) {
+	tests := []struct {
+		name string
+		hdr  [12]byte
+	}{
+		{"iNES", [12]byte{8, 16, 0x40}},
+		{"NES 2.0 32 KB", [12]byte{8, 16, 0x40, 0x08, 0, 0, 0x09}}, // only the first 8 KB are used
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, nes := testCart(t, testImage(tt.hdr, 0x20000, 0x20000))
+
+			nes.Memory.MemWrite(0x6000, 0x12)
+			nes.Memory.MemWrite(0x7fff, 0x34)
+			if v := nes.Memory.MemRead(0x6000); v != 0x12 {
+				t.Errorf("read $%02x at $6000, want $12", v)
+			}
+			if v := nes.Memory.MemRead(0x7fff); v != 0x34 {
+				t.Errorf("read $%02x at $7fff, want $34", v)
+			}
+
+			// write protect
+			nes.Memory.MemWrite(0xa001, 0xc0)
+			nes.Memory.MemWrite(0x6000, 0x56)
+			if v := nes.Memory.MemRead(0x6000); v != 0x12 {
+				t.Errorf("read $%02x at $6000 after a write while protected, want $12", v)
+			}
+
+			// disabled, the last value seen on the bus is read
+			nes.Memory.MemWrite(0xa001, 0x00)
+			nes.Memory.MemWrite(0x0000, 0x5a)
+			if v := nes.Memory.MemRead(0x6000); v != 0x5a {
+				t.Errorf("read $%02x at $6000 while disabled, want open bus $5a", v)
+			}
+		})
+	}
+}
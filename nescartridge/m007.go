@@ -0,0 +1,121 @@
+package nescartridge
+
+import (
+	"encoding/binary"
+	"io"
+	"unsafe"
+
+	"github.com/MagicalTux/gones/memory"
+	"github.com/MagicalTux/gones/nesppu"
+	"github.com/MagicalTux/gones/pkgnes"
+)
+
+const (
+	AxROM MapperType = 0x07 // Nintendo cartridge boards NES-AMROM, NES-ANROM, NES-AN1ROM, NES-AOROM and clone boards
+)
+
+func init() {
+	RegisterMapper(AxROM, func(data *Data) Mapper {
+		return &MapperAxROM{
+			data: data,
+			// only some AxROM boards (AMROM, AOROM) have bus conflicts, and submapper 2 tells us so
+			busConflicts: data.SubMapper == 2,
+		}
+	})
+}
+
+// https://www.nesdev.org/wiki/AxROM
+type MapperAxROM struct {
+	data *Data
+	mem  memory.Master
+	ppu  *nesppu.PPU
+
+	prg  memory.ROM
+	bank byte
+
+	busConflicts bool
+}
+
+func (m *MapperAxROM) setup(nes *pkgnes.NES) error {
+	m.mem = nes.Memory
+	m.ppu = nes.PPU
+	m.prg = memory.ROM(m.data.PRG())
+
+	if ram := m.data.newPRGRAM(); ram != nil {
+		nes.Memory.MapHandler(0x6000, 0x2000, ram)
+	}
+
+	// PPU $0000-$1FFF: 8 KB CHR RAM
+	nes.PPU.Memory.MapHandler(0x0000, 0x2000, m.data.CHR())
+
+	m.updateBanks()
+
+	return nil
+}
+
+func (m *MapperAxROM) updateBanks() {
+	// CPU $8000-$FFFF: 32 KB switchable PRG ROM bank
+	cnt := len(m.prg) >> 15
+	if cnt == 0 {
+		cnt = 1
+	}
+	n := int(m.bank&0x0f) % cnt
+	bank := memory.Slice(m.prg, 0x8000*n, 0x8000*n+0x8000)
+
+	m.mem.ClearMapping(0x8000, 0x8000)
+	if m.busConflicts {
+		// mapping the ROM first means our register will see the AND of the ROM and the written value
+		m.mem.MapHandler(0x8000, 0x8000, bank)
+		m.mem.MapHandler(0x8000, 0x8000, m)
+	} else {
+		m.mem.MapHandler(0x8000, 0x8000, m)
+		m.mem.MapHandler(0x8000, 0x8000, bank)
+	}
+
+	// Select 1 KB VRAM page for all 4 nametables
+	if m.bank&0x10 == 0 {
+		m.ppu.SetMirroring(nesppu.SingleScreenMirroring)
+	} else {
+		m.ppu.SetMirroring(nesppu.SingleScreen2Mirroring)
+	}
+}
+
+func (m *MapperAxROM) MemRead(offset uint16) byte {
+	// reads are handled by the ROM
+	return 0
+}
+
+func (m *MapperAxROM) MemWrite(offset uint16, v byte) byte {
+	// Bank select ($8000-$FFFF)
+	m.bank = v
+	m.updateBanks()
+	return 0
+}
+
+func (m *MapperAxROM) saveState(w io.Writer) error {
+	return binary.Write(w, binary.LittleEndian, m.bank)
+}
+
+func (m *MapperAxROM) loadState(r io.Reader) error {
+	if err := binary.Read(r, binary.LittleEndian, &m.bank); err != nil {
+		return err
+	}
+	m.updateBanks()
+	return nil
+}
+
+func (m *MapperAxROM) prgBankSize() int {
+	return 0x8000
+}
+
+func (m *MapperAxROM) Ptr() uintptr {
+	return uintptr(unsafe.Pointer(m))
+}
+
+func (m *MapperAxROM) String() string {
+	return "AxROM Mapper"
+}
+
+func (m *MapperAxROM) Length() uint16 {
+	return 0
+}
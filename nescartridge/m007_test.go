@@ -0,0 +1,56 @@
+package nescartridge
+
+import "testing"
+
+func TestAxROM(t *testing.T) {
+	// 128 KB PRG: 4 banks of 32 KB, each 1 KB of ROM contains its number
+	tests := []struct {
+		name      string
+		submapper byte
+		addr      uint16
+		v         byte
+		bank      int // bank mapped at $8000 after the write
+	}{
+		{"no conflicts", 0, 0x8000, 0x03, 3},
+		{"mirroring bit", 0, 0x8000, 0x12, 2},
+		{"bank wraps", 0, 0x8000, 0x05, 1},
+		{"conflict", 2, 0x8400, 0x03, 1},     // $01 & $03
+		{"conflict AND", 2, 0x8c00, 0x02, 2}, // $03 & $02
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, nes := testCart(t, testImage([12]byte{8, 0, 0x70, 0x08, tt.submapper << 4}, 0x20000, 0))
+
+			nes.Memory.MemWrite(tt.addr, tt.v)
+			if v, want := nes.Memory.MemRead(0x8000), byte(tt.bank*32); v != want {
+				t.Errorf("read $%02x at $8000, want $%02x", v, want)
+			}
+			if v, want := nes.Memory.MemRead(0xffff), byte(tt.bank*32+31); v != want {
+				t.Errorf("read $%02x at $ffff, want $%02x", v, want)
+			}
+		})
+	}
+}
+
+func TestAxROMMirroring(t *testing.T) {
+	_, nes := testCart(t, testImage([12]byte{8, 0, 0x70}, 0x20000, 0))
+
+	// all 4 nametables show the first page
+	nes.PPU.Memory.MemWrite(0x2000, 0x11)
+	if v := nes.PPU.Memory.MemRead(0x2c00); v != 0x11 {
+		t.Errorf("read $%02x at PPU $2c00 with the first page selected, want $11", v)
+	}
+
+	// and the second page with bit 4 set
+	nes.Memory.MemWrite(0x8000, 0x10)
+	nes.PPU.Memory.MemWrite(0x2400, 0x22)
+	if v := nes.PPU.Memory.MemRead(0x2800); v != 0x22 {
+		t.Errorf("read $%02x at PPU $2800 with the second page selected, want $22", v)
+	}
+
+	nes.Memory.MemWrite(0x8000, 0x00)
+	if v := nes.PPU.Memory.MemRead(0x2400); v != 0x11 {
+		t.Errorf("read $%02x at PPU $2400 with the first page selected again, want $11", v)
+	}
+}
@@ -1,13 +1,44 @@
 package nescartridge
 
-import "github.com/MagicalTux/gones/pkgnes"
+import (
+	"io"
 
-type MapperType byte
+	"github.com/MagicalTux/gones/pkgnes"
+)
+
+type MapperType uint16 // 8 bits for iNES files, 12 bits for NES 2.0
 
 type Mapper interface {
 	setup(nes *pkgnes.NES) error
 }
 
+// StatefulMapper is implemented by mappers that have internal state, such as
+// bank registers or IRQ counters, that needs to be included in save states.
+// loadState must re-apply the loaded state (banks, mirroring, etc).
+type StatefulMapper interface {
+	Mapper
+	saveState(w io.Writer) error
+	loadState(r io.Reader) error
+}
+
+// prgBanker is implemented by mappers switching PRG ROM in banks
+type prgBanker interface {
+	prgBankSize() int
+}
+
+// PRGBankSize returns the size of the PRG ROM banks switched by the mapper,
+// or the size of the PRG ROM (up to 32 KB) if the mapper doesn't switch it.
+// Tools such as disassemblers use it to split the ROM.
+func (d *Data) PRGBankSize() int {
+	if b, ok := d.Mapper.(prgBanker); ok {
+		return b.prgBankSize()
+	}
+	if d.prgSize < 0x8000 {
+		return d.prgSize
+	}
+	return 0x8000
+}
+
 var mappers = make(map[MapperType]func(*Data) Mapper)
 
 func RegisterMapper(mt MapperType, f func(*Data) Mapper) {
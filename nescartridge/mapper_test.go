@@ -0,0 +1,64 @@
+package nescartridge
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/MagicalTux/gones/pkgnes"
+)
+
+func TestPRGBankSize(t *testing.T) {
+	tests := []struct {
+		name string
+		hdr  [12]byte
+		prg  int
+		want int
+	}{
+		{"NROM-128", [12]byte{1, 1}, 0x4000, 0x4000},
+		{"NROM-256", [12]byte{2, 1}, 0x8000, 0x8000},
+		{"MMC1", [12]byte{8, 0, 0x10}, 0x20000, 0x4000},
+		{"UxROM", [12]byte{8, 0, 0x20}, 0x20000, 0x4000},
+		{"CNROM", [12]byte{2, 4, 0x30}, 0x8000, 0x8000},
+		{"MMC3", [12]byte{8, 0, 0x40}, 0x20000, 0x2000},
+		{"AxROM", [12]byte{8, 0, 0x70}, 0x20000, 0x8000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &Data{m: testImage(tt.hdr, tt.prg, int(tt.hdr[1])*0x2000)}
+			if err := d.parse(); err != nil {
+				t.Fatal(err)
+			}
+			if got := d.PRGBankSize(); got != tt.want {
+				t.Errorf("got $%x, want $%x", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetupErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		hdr  [12]byte
+		prg  int
+		err  string
+	}{
+		{"UxROM without PRG", [12]byte{0, 1, 0x20}, 0, "UxROM needs at least 16 KB of PRG ROM"},
+		{"UxROM 8 KB", [12]byte{0x34, 1, 0x20, 0x08, 0, 0x0f}, 0x2000, "UxROM needs at least 16 KB of PRG ROM"},
+		{"MMC3 without PRG", [12]byte{0, 1, 0x40}, 0, "MMC3 needs at least 8 KB of PRG ROM"},
+		{"MMC3 4 KB", [12]byte{0x30, 1, 0x40, 0x08, 0, 0x0f}, 0x1000, "MMC3 needs at least 8 KB of PRG ROM"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &Data{m: testImage(tt.hdr, tt.prg, 0x2000)}
+			if err := d.parse(); err != nil {
+				t.Fatalf("parse: %s", err)
+			}
+			err := d.Setup(pkgnes.New(d.Model()))
+			if err == nil || !strings.HasPrefix(err.Error(), tt.err) {
+				t.Errorf("got error %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
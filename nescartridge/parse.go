@@ -33,28 +33,25 @@ func (d *Data) parse() error {
 		return fmt.Errorf("bad file header")
 	}
 
-	d.numPRG = d.m[4] // Size of PRG ROM in 16 KB units
-	d.numCHR = d.m[5] // Size of CHR ROM in 8 KB units (Value 0 means the board uses CHR RAM)
 	flg6 := d.m[6]
 	flg7 := d.m[7]
-	d.numPRGram = int(d.m[8]) + 1 // Size of PRG RAM in 8 KB units (Value 0 infers 8 KB for compatibility)
-	//flg9 := d.m[9]
-	//flg10 := d.m[10]
-	// 11-15: Unused padding (should be filled with zero, but some rippers put their name across bytes 7-15)
-
-	iNes2Flag := (flg7 >> 2) & 3
-	if iNes2Flag == 2 {
-		log.Printf("Image is in ines2 format, not supported yet")
-		panic("ines2 format not supported")
-	}
 
 	d.mapperType = MapperType(flg6>>4 | flg7&0xf0)
-
 	d.hasTrainer = flg6&flgTrainer == flgTrainer
+	d.hasBattery = flg6&flgBatteryRAM == flgBatteryRAM
 	d.hasMirroring = flg6&flgMirroring == flgMirroring
 	d.ignoreMirroring = flg6&flgIgnoreMirr == flgIgnoreMirr
 
-	log.Printf("Parsed iNes1 file, %d*16kB PRG, %d*8kB CHR, %d*8kB PRG RAM, mapper=%d, trainer=%v mirroring=%v/%v", d.numPRG, d.numCHR, d.numPRGram, d.mapperType, d.hasTrainer, d.hasMirroring, d.ignoreMirroring)
+	iNes2Flag := (flg7 >> 2) & 3
+	if iNes2Flag == 2 {
+		d.parseNES2()
+	} else {
+		d.parseINES1(iNes2Flag)
+	}
+
+	if len(d.m) < d.prgOffset()+d.prgSize+d.chrSize {
+		return fmt.Errorf("file is too small for its header: %d bytes PRG and %d bytes CHR declared, file is %d bytes", d.prgSize, d.chrSize, len(d.m))
+	}
 
 	if f, ok := mappers[d.mapperType]; ok {
 		d.Mapper = f(d)
@@ -64,3 +61,106 @@ func (d *Data) parse() error {
 
 	return nil
 }
+
+func (d *Data) parseINES1(iNes2Flag byte) {
+	flg7 := d.m[7]
+	flg9 := d.m[9]
+	// 10: rarely used extension, ignored
+	// 11-15: Unused padding (should be filled with zero, but some rippers put their name across bytes 7-15)
+
+	if iNes2Flag != 0 || !bytes.Equal(d.m[12:16], []byte{0, 0, 0, 0}) {
+		// archaic iNES file with garbage in bytes 7-15 (typically "DiskDude!", which sets bits 2-3 of byte 7 to 1), only
+		// trust the lower nibble of the mapper number
+		// see: https://www.nesdev.org/wiki/INES#Variant_comparison
+		d.mapperType &= 0x0f
+		flg7 = 0
+		flg9 = 0
+	}
+
+	d.prgSize = int(d.m[4]) << 14 // Size of PRG ROM in 16 KB units
+	d.chrSize = int(d.m[5]) << 13 // Size of CHR ROM in 8 KB units (Value 0 means the board uses CHR RAM)
+	if d.chrSize == 0 {
+		d.chrRAMSize = 0x2000
+	}
+
+	// Size of PRG RAM in 8 KB units (Value 0 infers 8 KB for compatibility). Most boards can't address more
+	// than 8kB and a lot of files have a wrong value there, so we always assume 8kB, battery backed if the
+	// header says so.
+	if d.hasBattery {
+		d.prgNVRAMSize = 0x2000
+	} else {
+		d.prgRAMSize = 0x2000
+	}
+
+	switch {
+	case flg7&flgUnisys == flgUnisys:
+		d.Console = ConsoleVsSystem
+	case flg7&flgPlayChoice == flgPlayChoice:
+		d.Console = ConsolePlayChoice
+	}
+	if flg9&flgPAL == flgPAL {
+		d.Timing = TimingPAL
+	}
+
+	log.Printf("Parsed iNes1 file, %d*16kB PRG, %d*8kB CHR, mapper=%d, trainer=%v battery=%v mirroring=%v/%v", d.prgSize>>14, d.chrSize>>13, d.mapperType, d.hasTrainer, d.hasBattery, d.hasMirroring, d.ignoreMirroring)
+}
+
+// https://www.nesdev.org/wiki/NES_2.0
+func (d *Data) parseNES2() {
+	d.nes2 = true
+	d.Console = ConsoleType(d.m[7] & 3)
+
+	// 8: mapper MSB (bits 8-11 of mapper number) & submapper
+	d.mapperType |= MapperType(d.m[8]&0x0f) << 8
+	d.SubMapper = d.m[8] >> 4
+
+	// 9: PRG-ROM/CHR-ROM size MSB
+	d.prgSize = nes2RomSize(d.m[4], d.m[9]&0x0f, 0x4000)
+	d.chrSize = nes2RomSize(d.m[5], d.m[9]>>4, 0x2000)
+
+	// 10: PRG-RAM/EEPROM size, 11: CHR-RAM size
+	d.prgRAMSize = nes2RamSize(d.m[10] & 0x0f)
+	d.prgNVRAMSize = nes2RamSize(d.m[10] >> 4)
+	d.chrRAMSize = nes2RamSize(d.m[11] & 0x0f)
+	d.chrNVRAMSize = nes2RamSize(d.m[11] >> 4)
+
+	// 12: CPU/PPU timing
+	d.Timing = TimingMode(d.m[12] & 3)
+
+	// 13: Vs. System type (ignored) or extended console type
+	if d.Console == ConsoleExtended {
+		d.Console = ConsoleType(d.m[13] & 0x0f)
+	}
+
+	// 14: number of miscellaneous ROMs (stored after CHR data), ignored
+	// 15: default expansion device
+	d.Expansion = ExpansionDevice(d.m[15] & 0x3f)
+
+	log.Printf("Parsed NES 2.0 file, %d bytes PRG, %d bytes CHR, %d+%d bytes PRG RAM, %d+%d bytes CHR RAM, mapper=%d.%d, trainer=%v battery=%v mirroring=%v/%v timing=%s console=%s expansion=%d",
+		d.prgSize, d.chrSize, d.prgRAMSize, d.prgNVRAMSize, d.chrRAMSize, d.chrNVRAMSize, d.mapperType, d.SubMapper, d.hasTrainer, d.hasBattery, d.hasMirroring, d.ignoreMirroring, d.Timing, d.Console, d.Expansion)
+}
+
+// nes2RomSize computes the size of a ROM area in a NES 2.0 header, given its
+// LSB and MSB values and the size of one unit.
+func nes2RomSize(lsb, msb byte, unit int) int {
+	if msb != 0x0f {
+		return (int(msb)<<8 | int(lsb)) * unit
+	}
+	// exponent-multiplier notation: EEEEEEMM, size = 2^E * (MM*2+1)
+	exp := lsb >> 2
+	mul := int(lsb&3)*2 + 1
+	if exp > 30 {
+		// can't possibly fit in memory, will be rejected because the file is too small
+		exp = 30
+	}
+	return (1 << exp) * mul
+}
+
+// nes2RamSize returns the size of a RAM area given its shift count from a
+// NES 2.0 header. Zero means no RAM, other values mean 64<<shift bytes.
+func nes2RamSize(shift byte) int {
+	if shift == 0 {
+		return 0
+	}
+	return 64 << shift
+}
@@ -0,0 +1,194 @@
+package nescartridge
+
+import (
+	"io"
+	"log"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/MagicalTux/gones/pkgnes"
+)
+
+func TestMain(m *testing.M) {
+	// parsing and mappers log a lot of things, such as each bank switch
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// testImage returns a cartridge image with the given header bytes 4 to 15,
+// followed by prg and chr bytes of data. Each 1 KB of PRG and CHR is filled
+// with its number, so tests can tell which bank is mapped where.
+func testImage(hdr [12]byte, prg, chr int) []byte {
+	img := append([]byte(iNesHeader), hdr[:]...)
+	for i := 0; i < prg; i++ {
+		img = append(img, byte(i>>10))
+	}
+	for i := 0; i < chr; i++ {
+		img = append(img, byte(i>>10))
+	}
+	return img
+}
+
+// testCart parses img and sets it up on a new NES
+func testCart(t *testing.T, img []byte) (*Data, *pkgnes.NES) {
+	t.Helper()
+
+	d := &Data{m: img}
+	if err := d.parse(); err != nil {
+		t.Fatalf("parse: %s", err)
+	}
+	nes := pkgnes.New(d.Model())
+	if err := d.Setup(nes); err != nil {
+		t.Fatalf("setup: %s", err)
+	}
+	return d, nes
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		hdr      [12]byte
+		prg, chr int // data in the image
+		want     *Data
+	}{
+		{
+			name: "iNES NROM",
+			hdr:  [12]byte{2, 1, flgMirroring | flgBatteryRAM},
+			prg:  0x8000, chr: 0x2000,
+			want: &Data{prgSize: 0x8000, chrSize: 0x2000, prgNVRAMSize: 0x2000, hasMirroring: true, hasBattery: true},
+		},
+		{
+			name: "iNES MMC3 with CHR RAM, PAL",
+			hdr:  [12]byte{8, 0, 0x40, 0, 0, flgPAL},
+			prg:  0x20000,
+			want: &Data{prgSize: 0x20000, chrRAMSize: 0x2000, prgRAMSize: 0x2000, mapperType: MMC3, Timing: TimingPAL},
+		},
+		{
+			name: "archaic iNES with garbage",
+			hdr:  [12]byte{1, 1, 0x10, 'D', 'i', 's', 'k', 'D', 'u', 'd', 'e', '!'},
+			prg:  0x4000, chr: 0x2000,
+			want: &Data{prgSize: 0x4000, chrSize: 0x2000, prgRAMSize: 0x2000, mapperType: 1},
+		},
+		{
+			name: "iNES Vs. System",
+			hdr:  [12]byte{1, 1, 0, flgUnisys},
+			prg:  0x4000, chr: 0x2000,
+			want: &Data{prgSize: 0x4000, chrSize: 0x2000, prgRAMSize: 0x2000, Console: ConsoleVsSystem},
+		},
+		{
+			name: "NES 2.0 12 bits mapper and submapper",
+			// mapper $102.3, 32 KB PRG RAM + 8 KB PRG NVRAM, 16 KB CHR RAM, Dendy, Four Score
+			hdr: [12]byte{2, 0, 0x20, 0x08, 0x31, 0, 0x79, 0x08, byte(TimingDendy), 0, 0, byte(ExpansionFourScore)},
+			prg: 0x8000,
+			want: &Data{
+				prgSize: 0x8000, prgRAMSize: 0x8000, prgNVRAMSize: 0x2000, chrRAMSize: 0x4000,
+				mapperType: 0x102, SubMapper: 3, Timing: TimingDendy, Expansion: ExpansionFourScore, nes2: true,
+			},
+		},
+		{
+			name: "NES 2.0 ROM size MSB",
+			// $110 * 16 KB PRG, $201 * 8 KB CHR
+			hdr: [12]byte{0x10, 0x01, 0, 0x08, 0, 0x21},
+			prg: 0x110 * 0x4000, chr: 0x201 * 0x2000,
+			want: &Data{prgSize: 0x110 * 0x4000, chrSize: 0x201 * 0x2000, nes2: true},
+		},
+		{
+			name: "NES 2.0 exponent-multiplier sizes",
+			// PRG: 2^4*3 = 48 bytes, CHR: 2^10*1 = 1 KB
+			hdr: [12]byte{4<<2 | 1, 10 << 2, 0, 0x08, 0, 0xff},
+			prg: 48, chr: 0x400,
+			want: &Data{prgSize: 48, chrSize: 0x400, nes2: true},
+		},
+		{
+			name: "NES 2.0 extended console type",
+			hdr:  [12]byte{1, 1, 0, 0x08 | byte(ConsoleExtended), 0, 0, 0, 0, 0, 5},
+			prg:  0x4000, chr: 0x2000,
+			want: &Data{prgSize: 0x4000, chrSize: 0x2000, Console: 5, nes2: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &Data{m: testImage(tt.hdr, tt.prg, tt.chr)}
+			// mappers aren't registered for all the tested numbers
+			err := d.parse()
+			if err != nil && !strings.HasPrefix(err.Error(), "unsupported mapper") {
+				t.Fatal(err)
+			}
+
+			got := []any{d.prgSize, d.chrSize, d.prgRAMSize, d.prgNVRAMSize, d.chrRAMSize, d.chrNVRAMSize,
+				d.mapperType, d.SubMapper, d.Timing, d.Console, d.Expansion, d.nes2, d.hasBattery, d.hasMirroring}
+			w := tt.want
+			want := []any{w.prgSize, w.chrSize, w.prgRAMSize, w.prgNVRAMSize, w.chrRAMSize, w.chrNVRAMSize,
+				w.mapperType, w.SubMapper, w.Timing, w.Console, w.Expansion, w.nes2, w.hasBattery, w.hasMirroring}
+			for i := range got {
+				if got[i] != want[i] {
+					t.Errorf("got %v, want %v", got, want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		img  []byte
+		err  string
+	}{
+		{"short", []byte("NES\x1a"), "file is too small"},
+		{"bad header", make([]byte, 16), "bad file header"},
+		{"truncated", testImage([12]byte{2, 1}, 0x4000, 0), "file is too small for its header"},
+		{"unknown mapper", testImage([12]byte{1, 1, 0xf0}, 0x4000, 0x2000), "unsupported mapper 15"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &Data{m: tt.img}
+			err := d.parse()
+			if err == nil || !strings.HasPrefix(err.Error(), tt.err) {
+				t.Errorf("got error %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestPRGRAMSize(t *testing.T) {
+	tests := []struct {
+		name string
+		ram  byte // NES 2.0 byte 10: PRG NVRAM and RAM shift counts
+		want int
+	}{
+		{"none", 0x00, 0},
+		{"8 KB", 0x07, 0x2000},
+		{"8 KB NVRAM", 0x70, 0x2000},
+		{"8 KB + 8 KB NVRAM", 0x77, 0x4000},
+		{"8 KB + 2 KB NVRAM", 0x57, 0x4000},
+		{"2 KB + 512 B NVRAM", 0x05 | 0x30, 0x1000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &Data{m: testImage([12]byte{2, 1, 0x10, 0x08, 0, 0, tt.ram}, 0x8000, 0x2000)}
+			if err := d.parse(); err != nil {
+				t.Fatal(err)
+			}
+			ram := d.newPRGRAM()
+			if len(ram) != tt.want {
+				t.Fatalf("got %d bytes of PRG RAM, want %d", len(ram), tt.want)
+			}
+
+			// every byte must be addressable on its own
+			for i := range ram {
+				ram.MemWrite(uint16(i), byte(i*7+i>>8))
+			}
+			for i := range ram {
+				if v := ram.MemRead(uint16(i)); v != byte(i*7+i>>8) {
+					t.Fatalf("read $%02x at $%04x, want $%02x", v, i, byte(i*7+i>>8))
+				}
+			}
+		})
+	}
+}
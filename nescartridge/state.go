@@ -0,0 +1,40 @@
+package nescartridge
+
+import (
+	"crypto/sha256"
+	"io"
+)
+
+// Hash returns the SHA-256 hash of the ROM data (trainer, PRG and CHR),
+// ignoring the header so fixing a bad header doesn't invalidate save states.
+func (d *Data) Hash() [32]byte {
+	return sha256.Sum256(d.m[16:])
+}
+
+// SaveState writes PRG RAM, CHR RAM and the state of the mapper to w
+func (d *Data) SaveState(w io.Writer) error {
+	if _, err := w.Write(d.prgRAM); err != nil {
+		return err
+	}
+	if _, err := w.Write(d.chrRAM); err != nil {
+		return err
+	}
+	if m, ok := d.Mapper.(StatefulMapper); ok {
+		return m.saveState(w)
+	}
+	return nil
+}
+
+// LoadState restores a state written by SaveState
+func (d *Data) LoadState(r io.Reader) error {
+	if _, err := io.ReadFull(r, d.prgRAM); err != nil {
+		return err
+	}
+	if _, err := io.ReadFull(r, d.chrRAM); err != nil {
+		return err
+	}
+	if m, ok := d.Mapper.(StatefulMapper); ok {
+		return m.loadState(r)
+	}
+	return nil
+}
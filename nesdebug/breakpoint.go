@@ -0,0 +1,257 @@
+package nesdebug
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/MagicalTux/gones/cpu6502"
+	"github.com/MagicalTux/gones/memory"
+)
+
+// AnyAddress can be used as Breakpoint address to break on a condition
+// regardless of the current PC
+const AnyAddress = -1
+
+// Breakpoint stops the machine before the instruction at Addr is run, if
+// Cond is nil or returns true
+type Breakpoint struct {
+	ID   int
+	Addr int                         // address of the instruction, or AnyAddress
+	Cond func(cpu *cpu6502.CPU) bool // optional condition
+	Desc string                      // description of the condition
+}
+
+func (bp *Breakpoint) match(cpu *cpu6502.CPU) bool {
+	if bp.Addr != AnyAddress && uint16(bp.Addr) != cpu.PC {
+		return false
+	}
+	return bp.Cond == nil || bp.Cond(cpu)
+}
+
+func (bp *Breakpoint) String() string {
+	var s string
+	if bp.Addr == AnyAddress {
+		s = fmt.Sprintf("#%d: breakpoint anywhere", bp.ID)
+	} else {
+		s = fmt.Sprintf("#%d: breakpoint at $%04x", bp.ID, bp.Addr)
+	}
+	if bp.Desc != "" {
+		s += " if " + bp.Desc
+	}
+	return s
+}
+
+// Watchpoint stops the machine after the CPU reads or writes an address
+// between Start and End (inclusive). The machine stops before the next
+// instruction.
+type Watchpoint struct {
+	ID         int
+	Start, End uint16
+	Read       bool
+	Write      bool
+
+	watch *memory.Watch // installed on the CPU bus
+}
+
+func (w *Watchpoint) String() string {
+	var mode string
+	switch {
+	case w.Read && w.Write:
+		mode = "rw"
+	case w.Write:
+		mode = "w"
+	default:
+		mode = "r"
+	}
+	if w.Start == w.End {
+		return fmt.Sprintf("#%d: watchpoint (%s) at $%04x", w.ID, mode, w.Start)
+	}
+	return fmt.Sprintf("#%d: watchpoint (%s) at $%04x-$%04x", w.ID, mode, w.Start, w.End)
+}
+
+// AddBreakpoint adds a breakpoint at the given address (or AnyAddress), with
+// an optional condition
+func (d *Debugger) AddBreakpoint(addr int, cond func(cpu *cpu6502.CPU) bool, desc string) *Breakpoint {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	bp := &Breakpoint{ID: d.nextID, Addr: addr, Cond: cond, Desc: desc}
+	d.nextID += 1
+	d.breakpoints = append(d.breakpoints, bp)
+	return bp
+}
+
+// AddWatchpoint adds a watchpoint on CPU accesses to start~end. The machine
+// is paused while the watchpoint is installed on the CPU bus.
+func (d *Debugger) AddWatchpoint(start, end uint16, read, write bool) *Watchpoint {
+	d.mu.Lock()
+	w := &Watchpoint{ID: d.nextID, Start: start, End: end, Read: read, Write: write}
+	w.watch = &memory.Watch{Start: start, End: end, Read: read, Write: write, Func: func(a memory.Access) {
+		d.access(w, a)
+	}}
+	d.nextID += 1
+	d.watchpoints = append(d.watchpoints, w)
+	d.mu.Unlock()
+
+	// AddWatch pauses the machine, which can't happen while holding d.mu as
+	// the CPU takes the lock in hook
+	d.nes.AddWatch(w.watch)
+	return w
+}
+
+// Remove removes the breakpoint or watchpoint with the given ID
+func (d *Debugger) Remove(id int) bool {
+	d.mu.Lock()
+	for n, bp := range d.breakpoints {
+		if bp.ID == id {
+			d.breakpoints = append(d.breakpoints[:n], d.breakpoints[n+1:]...)
+			d.mu.Unlock()
+			return true
+		}
+	}
+	var found *Watchpoint
+	for n, w := range d.watchpoints {
+		if w.ID == id {
+			d.watchpoints = append(d.watchpoints[:n], d.watchpoints[n+1:]...)
+			found = w
+			break
+		}
+	}
+	d.mu.Unlock()
+
+	if found == nil {
+		return false
+	}
+	d.nes.RemoveWatch(found.watch)
+	return true
+}
+
+// List returns all breakpoints and watchpoints
+func (d *Debugger) List() ([]*Breakpoint, []*Watchpoint) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return append([]*Breakpoint(nil), d.breakpoints...), append([]*Watchpoint(nil), d.watchpoints...)
+}
+
+// access is called by the watch of w on each matching CPU memory access. It
+// is called from the CPU (in hook's caller), so d.mu is not held.
+func (d *Debugger) access(w *Watchpoint, a memory.Access) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.hit == nil {
+		d.hit = &Event{Reason: ReasonWatchpoint, Watchpoint: w, Access: a}
+	}
+}
+
+// ParseCondition parses a condition on registers such as "A==$10" or
+// "X>=4 && PC<$8000". Registers are A, X, Y, S, P and PC, values are decimal
+// unless prefixed with $ or 0x.
+func ParseCondition(s string) (func(cpu *cpu6502.CPU) bool, error) {
+	var conds []func(cpu *cpu6502.CPU) bool
+
+	for _, part := range strings.Split(s, "&&") {
+		c, err := parseComparison(strings.TrimSpace(part))
+		if err != nil {
+			return nil, err
+		}
+		conds = append(conds, c)
+	}
+
+	return func(cpu *cpu6502.CPU) bool {
+		for _, c := range conds {
+			if !c(cpu) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+func parseComparison(s string) (func(cpu *cpu6502.CPU) bool, error) {
+	// longer operators first so "<=" isn't parsed as "<"
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">", "&"} {
+		pos := strings.Index(s, op)
+		if pos == -1 {
+			continue
+		}
+
+		reg, err := register(strings.TrimSpace(s[:pos]))
+		if err != nil {
+			return nil, err
+		}
+		v, err := parseValue(strings.TrimSpace(s[pos+len(op):]))
+		if err != nil {
+			return nil, err
+		}
+
+		switch op {
+		case "==":
+			return func(cpu *cpu6502.CPU) bool { return reg(cpu) == v }, nil
+		case "!=":
+			return func(cpu *cpu6502.CPU) bool { return reg(cpu) != v }, nil
+		case "<=":
+			return func(cpu *cpu6502.CPU) bool { return reg(cpu) <= v }, nil
+		case ">=":
+			return func(cpu *cpu6502.CPU) bool { return reg(cpu) >= v }, nil
+		case "<":
+			return func(cpu *cpu6502.CPU) bool { return reg(cpu) < v }, nil
+		case ">":
+			return func(cpu *cpu6502.CPU) bool { return reg(cpu) > v }, nil
+		case "&":
+			// flag test, for example P&$01 for carry
+			return func(cpu *cpu6502.CPU) bool { return reg(cpu)&v != 0 }, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid condition %q", s)
+}
+
+func register(name string) (func(cpu *cpu6502.CPU) uint16, error) {
+	switch strings.ToUpper(name) {
+	case "A":
+		return func(cpu *cpu6502.CPU) uint16 { return uint16(cpu.A) }, nil
+	case "X":
+		return func(cpu *cpu6502.CPU) uint16 { return uint16(cpu.X) }, nil
+	case "Y":
+		return func(cpu *cpu6502.CPU) uint16 { return uint16(cpu.Y) }, nil
+	case "S":
+		return func(cpu *cpu6502.CPU) uint16 { return uint16(cpu.S) }, nil
+	case "P":
+		return func(cpu *cpu6502.CPU) uint16 { return uint16(cpu.P) }, nil
+	case "PC":
+		return func(cpu *cpu6502.CPU) uint16 { return cpu.PC }, nil
+	default:
+		return nil, fmt.Errorf("unknown register %q", name)
+	}
+}
+
+// parseValue parses a decimal value, or hexadecimal if prefixed by $ or 0x
+func parseValue(s string) (uint16, error) {
+	base := 10
+	switch {
+	case strings.HasPrefix(s, "$"):
+		s, base = s[1:], 16
+	case strings.HasPrefix(s, "0x"), strings.HasPrefix(s, "0X"):
+		s, base = s[2:], 16
+	}
+	if s == "" {
+		return 0, errors.New("missing value")
+	}
+	v, err := strconv.ParseUint(s, base, 16)
+	return uint16(v), err
+}
+
+// parseAddr parses an address, which is hexadecimal with or without $ or 0x
+// prefix
+func parseAddr(s string) (uint16, error) {
+	s = strings.TrimPrefix(s, "$")
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X")
+	v, err := strconv.ParseUint(s, 16, 16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid address %q", s)
+	}
+	return uint16(v), nil
+}
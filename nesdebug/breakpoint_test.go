@@ -0,0 +1,112 @@
+package nesdebug
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/MagicalTux/gones/clock"
+	"github.com/MagicalTux/gones/memory"
+	"github.com/MagicalTux/gones/pkgnes"
+)
+
+func TestWatchpoint(t *testing.T) {
+	nes := pkgnes.New(pkgnes.NTSC)
+	// LDA #$42, STA $0300, NOP, JMP $0206
+	for n, v := range []byte{0xa9, 0x42, 0x8d, 0x00, 0x03, 0xea, 0x4c, 0x06, 0x02} {
+		nes.Memory.MemWrite(0x200+uint16(n), v)
+	}
+	nes.CPU.PC = 0x200
+
+	d := New(nes)
+	w := d.AddWatchpoint(0x300, 0x3ff, false, true)
+
+	if err := nes.Clk.RunCycles(1000); !errors.Is(err, clock.ErrStopped) {
+		t.Fatalf("RunCycles: %v, want %s", err, clock.ErrStopped)
+	}
+	ev := d.Wait()
+	if ev.Reason != ReasonWatchpoint || ev.Watchpoint != w {
+		t.Fatalf("stopped by %s, want watchpoint #%d", ev, w.ID)
+	}
+	if want := (memory.Access{Addr: 0x300, Value: 0x42, Write: true}); ev.Access != want {
+		t.Errorf("access %s, want %s", ev.Access, want)
+	}
+	if ev.PC != 0x205 {
+		t.Errorf("stopped at $%04x, want $0205", ev.PC)
+	}
+
+	if !d.Remove(w.ID) {
+		t.Fatalf("watchpoint #%d not removed", w.ID)
+	}
+	if d.Remove(w.ID) {
+		t.Errorf("watchpoint #%d removed twice", w.ID)
+	}
+	nes.Memory.MemWrite(0x300, 0x01)
+	if d.hit != nil {
+		t.Errorf("removed watchpoint hit by %s", d.hit.Access)
+	}
+
+	d.AddWatchpoint(0x300, 0x300, true, true)
+	d.Detach()
+	nes.Clk.Stop()
+	nes.Memory.MemRead(0x300)
+	if d.hit != nil {
+		t.Errorf("watchpoint hit after Detach by %s", d.hit.Access)
+	}
+}
+
+// nmiMachine returns a NES looping at $0200, with its NMI handler at $0300
+func nmiMachine() *pkgnes.NES {
+	nes := pkgnes.New(pkgnes.NTSC)
+	rom := memory.NewRAM(0x8000)
+	rom[0x7ffa], rom[0x7ffb] = 0x00, 0x03
+	nes.Memory.MapHandler(0x8000, 0x8000, rom)
+
+	// $0200: JMP $0200, $0300: NOP, RTI
+	for n, v := range []byte{0x4c, 0x00, 0x02} {
+		nes.Memory.MemWrite(0x200+uint16(n), v)
+	}
+	nes.Memory.MemWrite(0x300, 0xea)
+	nes.Memory.MemWrite(0x301, 0x40)
+	nes.CPU.PC = 0x200
+	return nes
+}
+
+func TestBreakpointNMIHandler(t *testing.T) {
+	nes := nmiMachine()
+	d := New(nes)
+	bp := d.AddBreakpoint(0x300, nil, "")
+
+	if err := nes.Clk.RunCycles(1000); err != nil {
+		t.Fatalf("RunCycles: %v before the NMI", err)
+	}
+	nes.CPU.NMI()
+	if err := nes.Clk.RunCycles(1000); !errors.Is(err, clock.ErrStopped) {
+		t.Fatalf("RunCycles: %v, want %s", err, clock.ErrStopped)
+	}
+	ev := d.Wait()
+	if ev.Reason != ReasonBreakpoint || ev.Breakpoint != bp || ev.PC != 0x300 {
+		t.Fatalf("stopped by %s, want breakpoint #%d at $0300", ev, bp.ID)
+	}
+	if nes.CPU.PC != 0x300 {
+		t.Errorf("CPU at $%04x, want $0300 before the first instruction of the handler", nes.CPU.PC)
+	}
+}
+
+func TestStepIntoNMI(t *testing.T) {
+	nes := nmiMachine()
+	d := New(nes)
+	defer nes.Clk.Stop()
+	d.AddBreakpoint(0x200, nil, "")
+
+	if err := nes.Clk.RunCycles(1000); !errors.Is(err, clock.ErrStopped) {
+		t.Fatalf("RunCycles: %v, want %s", err, clock.ErrStopped)
+	}
+	d.Wait()
+
+	// the NMI is taken after the JMP, stepping stops before the handler runs
+	nes.CPU.NMI()
+	d.StepInto()
+	if ev := d.Wait(); ev.Reason != ReasonStep || ev.PC != 0x300 {
+		t.Errorf("stopped by %s, want a step to $0300", ev)
+	}
+}
@@ -0,0 +1,304 @@
+// Package nesdebug implements a debugger for the NES CPU, with breakpoints,
+// watchpoints and stepping. It can be used from code, or interactively via
+// REPL.
+package nesdebug
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/MagicalTux/gones/cpu6502"
+	"github.com/MagicalTux/gones/memory"
+	"github.com/MagicalTux/gones/pkgnes"
+)
+
+// Reason tells why the machine stopped
+type Reason int
+
+const (
+	ReasonHalt       Reason = iota // Halt was called
+	ReasonBreakpoint               // a breakpoint was hit
+	ReasonWatchpoint               // a watchpoint was hit
+	ReasonStep                     // a step completed
+	ReasonScanline                 // the PPU reached the requested scanline
+)
+
+func (r Reason) String() string {
+	switch r {
+	case ReasonHalt:
+		return "halted"
+	case ReasonBreakpoint:
+		return "breakpoint"
+	case ReasonWatchpoint:
+		return "watchpoint"
+	case ReasonStep:
+		return "step"
+	case ReasonScanline:
+		return "scanline"
+	default:
+		return fmt.Sprintf("Reason(%d)", int(r))
+	}
+}
+
+// Event describes why and where the machine stopped
+type Event struct {
+	Reason     Reason
+	PC         uint16
+	Breakpoint *Breakpoint   // breakpoint that was hit, if any
+	Watchpoint *Watchpoint   // watchpoint that was hit, if any
+	Access     memory.Access // memory access that triggered the watchpoint
+}
+
+func (e *Event) String() string {
+	switch e.Reason {
+	case ReasonBreakpoint:
+		return fmt.Sprintf("%s #%d at $%04x", e.Reason, e.Breakpoint.ID, e.PC)
+	case ReasonWatchpoint:
+		return fmt.Sprintf("%s #%d: %s, stopped at $%04x", e.Reason, e.Watchpoint.ID, e.Access, e.PC)
+	default:
+		return fmt.Sprintf("%s at $%04x", e.Reason, e.PC)
+	}
+}
+
+// stepping modes
+type stepMode int
+
+const (
+	stepNone     stepMode = iota
+	stepInto              // stop before the next instruction
+	stepOut               // stop after returning from the current subroutine
+	stepScanline          // stop when the PPU reaches a given scanline
+)
+
+// Debugger controls the CPU of a NES. Breakpoints and watchpoints are only
+// checked while the machine is run by its clock (see pkgnes.NES.Start).
+type Debugger struct {
+	nes *pkgnes.NES
+
+	mu          sync.Mutex
+	breakpoints []*Breakpoint
+	watchpoints []*Watchpoint
+	nextID      int
+
+	halt     bool     // stop at next instruction
+	skip     bool     // do not stop at the next instruction, used when resuming
+	step     stepMode // current stepping mode
+	stepS    byte     // stack pointer at the start of stepOut
+	stepLine uint16   // scanline for stepScanline
+	lastOp   byte     // last opcode run, for stepOut
+	lastLine uint16   // scanline seen before the last instruction, for stepScanline
+	hit      *Event   // watchpoint hit during the last instruction
+	halted   bool     // machine is stopped by the debugger
+
+	events chan *Event
+}
+
+// New attaches a new debugger to the CPU of the given NES. Only one debugger
+// can be attached at a time.
+func New(nes *pkgnes.NES) *Debugger {
+	d := &Debugger{
+		nes:    nes,
+		nextID: 1,
+		events: make(chan *Event, 1),
+	}
+	nes.CPU.Hook = d.hook
+	return d
+}
+
+// Events returns a channel receiving an event each time the machine is
+// stopped by the debugger
+func (d *Debugger) Events() <-chan *Event {
+	return d.events
+}
+
+// Wait waits for the machine to be stopped by the debugger
+func (d *Debugger) Wait() *Event {
+	return <-d.events
+}
+
+// hook is called by the CPU before each instruction, and returns false to
+// stop the machine
+func (d *Debugger) hook(cpu *cpu6502.CPU) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ev := d.check(cpu)
+	d.lastOp = d.Peek(cpu.PC)
+	d.lastLine = d.nes.PPU.Scanline()
+	if ev == nil {
+		return true
+	}
+
+	d.halted = true
+	d.halt = false
+	d.step = stepNone
+	d.nes.Clk.Stop()
+
+	select {
+	case d.events <- ev:
+	default:
+		// nobody is waiting for events, drop the previous one
+		select {
+		case <-d.events:
+		default:
+		}
+		d.events <- ev
+	}
+	return false
+}
+
+// check returns an event if the machine needs to stop before the current
+// instruction
+func (d *Debugger) check(cpu *cpu6502.CPU) *Event {
+	if ev := d.hit; ev != nil {
+		// watchpoint triggered during the previous instruction
+		d.hit = nil
+		ev.PC = cpu.PC
+		return ev
+	}
+	if d.skip {
+		// resuming, run at least one instruction
+		d.skip = false
+		return nil
+	}
+	if d.halt {
+		return &Event{Reason: ReasonHalt, PC: cpu.PC}
+	}
+
+	switch d.step {
+	case stepInto:
+		return &Event{Reason: ReasonStep, PC: cpu.PC}
+	case stepOut:
+		if (d.lastOp == 0x60 || d.lastOp == 0x40) && cpu.S > d.stepS {
+			// RTS or RTI unwound the stack past where we started
+			return &Event{Reason: ReasonStep, PC: cpu.PC}
+		}
+	case stepScanline:
+		if line := d.nes.PPU.Scanline(); line == d.stepLine && d.lastLine != line {
+			return &Event{Reason: ReasonScanline, PC: cpu.PC}
+		}
+	}
+
+	for _, bp := range d.breakpoints {
+		if bp.match(cpu) {
+			return &Event{Reason: ReasonBreakpoint, PC: cpu.PC, Breakpoint: bp}
+		}
+	}
+	return nil
+}
+
+// resume starts the machine with the given stepping mode
+func (d *Debugger) resume(mode stepMode) {
+	d.mu.Lock()
+	d.step = mode
+	d.skip = true
+	d.halt = false
+	d.halted = false
+	d.mu.Unlock()
+
+	d.nes.Clk.Start()
+}
+
+// Halted returns true if the machine was stopped by the debugger
+func (d *Debugger) Halted() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.halted
+}
+
+// Halt stops the machine before the next instruction
+func (d *Debugger) Halt() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.halt = true
+}
+
+// stop stops the machine between two instructions and marks it halted by the
+// debugger, like Halt followed by Wait. It doesn't wait for the hook, which
+// is never called if the clock isn't running or the CPU faulted, and drops
+// the pending event if any. It returns true if the machine was running or
+// already halted by the debugger.
+func (d *Debugger) stop() bool {
+	// pausing must happen before locking, the CPU takes the lock in hook
+	running := d.nes.Clk.Pause()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	halted := d.halted
+	d.halted = true
+	d.halt = false
+	d.step = stepNone
+	select {
+	case <-d.events:
+	default:
+	}
+	return running || halted
+}
+
+// Continue resumes the machine until a breakpoint or watchpoint is hit
+func (d *Debugger) Continue() {
+	d.resume(stepNone)
+}
+
+// StepInto runs a single instruction
+func (d *Debugger) StepInto() {
+	d.resume(stepInto)
+}
+
+// StepOver runs a single instruction, or a whole subroutine if the current
+// instruction is a JSR
+func (d *Debugger) StepOver() {
+	cpu := d.nes.CPU
+	if d.Peek(cpu.PC) != 0x20 {
+		d.StepInto()
+		return
+	}
+
+	// stop once we return from the subroutine, which means the stack pointer is back to its current value
+	d.mu.Lock()
+	d.stepS = cpu.S - 2
+	d.mu.Unlock()
+	d.resume(stepOut)
+}
+
+// StepOut runs until the current subroutine returns (RTS or RTI)
+func (d *Debugger) StepOut() {
+	d.mu.Lock()
+	d.stepS = d.nes.CPU.S
+	d.mu.Unlock()
+	d.resume(stepOut)
+}
+
+// RunToScanline runs until the PPU starts rendering the given scanline
+func (d *Debugger) RunToScanline(line uint16) {
+	d.mu.Lock()
+	d.stepLine = line
+	d.mu.Unlock()
+	d.resume(stepScanline)
+}
+
+// Detach removes the debugger from the CPU and resumes the machine if it was
+// stopped by the debugger
+func (d *Debugger) Detach() {
+	// the CPU's hook can only be replaced while it isn't running
+	running := d.nes.Clk.Pause()
+
+	d.mu.Lock()
+	halted := d.halted
+	d.halted = false
+	watchpoints := d.watchpoints
+	d.watchpoints = nil
+	d.mu.Unlock()
+
+	for _, w := range watchpoints {
+		d.nes.RemoveWatch(w.watch)
+	}
+
+	d.nes.CPU.Hook = nil
+	if halted || running {
+		d.nes.Clk.Start()
+	}
+}
@@ -0,0 +1,423 @@
+package nesdebug
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// GDB register numbers, registers are sent in this order by the "g" packet
+// with PC as 16 bits little endian and others as 8 bits.
+const (
+	gdbRegA = iota
+	gdbRegX
+	gdbRegY
+	gdbRegS
+	gdbRegP
+	gdbRegPC
+	gdbRegCount
+)
+
+// gdbPacketSize is the largest packet exchanged with the client, including
+// the "$" and "#xx" framing. It is advertised in hex by qSupported.
+const gdbPacketSize = 0x1000
+
+// ServeGDB listens on addr (for example "localhost:2345") for connections
+// from GDB or any tool using the GDB remote serial protocol, and serves them
+// one at a time. The machine is halted when a client connects, and resumed
+// when it detaches.
+//
+// See: https://sourceware.org/gdb/current/onlinedocs/gdb.html/Remote-Protocol.html
+func (d *Debugger) ServeGDB(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	log.Printf("GDB: listening on %s", l.Addr())
+
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		log.Printf("GDB: connection from %s", c.RemoteAddr())
+		if err := d.serveGDBConn(c); err != nil && err != io.EOF {
+			log.Printf("GDB: %s", err)
+		}
+		c.Close()
+	}
+}
+
+// gdbConn is a client connection
+type gdbConn struct {
+	d  *Debugger
+	c  net.Conn
+	wl sync.Mutex
+
+	packets   chan string   // packets received
+	interrupt chan struct{} // Ctrl-C received
+	done      chan struct{} // closed once the connection isn't served anymore
+	err       error         // read error, valid once packets is closed
+	closed    bool          // packets was closed while running
+
+	points        map[string]int // breakpoints and watchpoints set by the client
+	resumeOnClose bool           // the machine was running or halted by the debugger when the client connected
+}
+
+func (d *Debugger) serveGDBConn(c net.Conn) error {
+	g := &gdbConn{
+		d:         d,
+		c:         c,
+		packets:   make(chan string),
+		interrupt: make(chan struct{}, 1),
+		done:      make(chan struct{}),
+		points:    make(map[string]int),
+	}
+	defer close(g.done)
+	defer g.cleanup()
+
+	go g.readLoop()
+
+	// the client expects a stopped target
+	g.resumeOnClose = d.stop()
+
+	for !g.closed {
+		select {
+		case p, ok := <-g.packets:
+			if !ok {
+				return g.err
+			}
+			if done := g.handle(p); done {
+				return nil
+			}
+		case <-g.interrupt:
+			// already stopped, there is no stop to report
+		}
+	}
+	return g.err
+}
+
+// cleanup removes breakpoints and watchpoints set by the client, and resumes
+// the machine
+func (g *gdbConn) cleanup() {
+	for _, id := range g.points {
+		g.d.Remove(id)
+	}
+	if g.resumeOnClose && g.d.Halted() {
+		g.d.Continue()
+	}
+}
+
+func (g *gdbConn) readLoop() {
+	defer close(g.packets)
+
+	r := bufio.NewReader(g.c)
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			g.err = err
+			return
+		}
+		switch b {
+		case 0x03:
+			// Ctrl-C
+			select {
+			case g.interrupt <- struct{}{}:
+			default:
+			}
+			continue
+		case '$':
+		default:
+			// acks, or garbage
+			continue
+		}
+
+		data, err := r.ReadString('#')
+		if err != nil {
+			g.err = err
+			return
+		}
+		data = data[:len(data)-1]
+
+		var cs [2]byte
+		if _, err := io.ReadFull(r, cs[:]); err != nil {
+			g.err = err
+			return
+		}
+		if v, err := strconv.ParseUint(string(cs[:]), 16, 8); err != nil || byte(v) != checksum(data) {
+			g.write("-")
+			continue
+		}
+		g.write("+")
+		select {
+		case g.packets <- data:
+		case <-g.done:
+			return
+		}
+	}
+}
+
+func checksum(s string) byte {
+	var cs byte
+	for i := 0; i < len(s); i++ {
+		cs += s[i]
+	}
+	return cs
+}
+
+func (g *gdbConn) write(s string) {
+	g.wl.Lock()
+	defer g.wl.Unlock()
+
+	io.WriteString(g.c, s)
+}
+
+func (g *gdbConn) send(data string) {
+	g.write(fmt.Sprintf("$%s#%02x", data, checksum(data)))
+}
+
+// handle handles a packet and returns true if the client detached
+func (g *gdbConn) handle(p string) bool {
+	if p == "" {
+		g.send("")
+		return false
+	}
+	d := g.d
+	cpu := d.nes.CPU
+
+	switch p[0] {
+	case '?':
+		g.send("S05")
+	case 'g':
+		g.send(hex.EncodeToString(g.regs()))
+	case 'G':
+		buf, err := hex.DecodeString(p[1:])
+		if err != nil || len(buf) != gdbRegCount+1 {
+			g.send("E01")
+			return false
+		}
+		cpu.A, cpu.X, cpu.Y, cpu.S, cpu.P = buf[0], buf[1], buf[2], buf[3], buf[4]
+		cpu.PC = uint16(buf[5]) | uint16(buf[6])<<8
+		g.send("OK")
+	case 'p':
+		n, err := strconv.ParseUint(p[1:], 16, 8)
+		if err != nil || n >= gdbRegCount {
+			g.send("E01")
+			return false
+		}
+		regs := g.regs()
+		if n == gdbRegPC {
+			g.send(hex.EncodeToString(regs[n : n+2]))
+		} else {
+			g.send(hex.EncodeToString(regs[n : n+1]))
+		}
+	case 'P':
+		reg, val, ok := strings.Cut(p[1:], "=")
+		n, err := strconv.ParseUint(reg, 16, 8)
+		buf, err2 := hex.DecodeString(val)
+		if !ok || err != nil || err2 != nil || len(buf) == 0 || n >= gdbRegCount {
+			g.send("E01")
+			return false
+		}
+		switch n {
+		case gdbRegA:
+			cpu.A = buf[0]
+		case gdbRegX:
+			cpu.X = buf[0]
+		case gdbRegY:
+			cpu.Y = buf[0]
+		case gdbRegS:
+			cpu.S = buf[0]
+		case gdbRegP:
+			cpu.P = buf[0]
+		case gdbRegPC:
+			if len(buf) < 2 {
+				g.send("E01")
+				return false
+			}
+			cpu.PC = uint16(buf[0]) | uint16(buf[1])<<8
+		}
+		g.send("OK")
+	case 'm':
+		addr, ln, err := parseAddrLen(p[1:])
+		if err != nil {
+			g.send("E01")
+			return false
+		}
+		if max := (gdbPacketSize - 4) / 2; ln > max {
+			// replies may be shorter than requested, the client reads the rest next
+			ln = max
+		}
+		buf := make([]byte, ln)
+		for i := range buf {
+			buf[i] = d.Peek(addr + uint16(i))
+		}
+		g.send(hex.EncodeToString(buf))
+	case 'M':
+		al, val, ok := strings.Cut(p[1:], ":")
+		addr, ln, err := parseAddrLen(al)
+		buf, err2 := hex.DecodeString(val)
+		if !ok || err != nil || err2 != nil || len(buf) != ln {
+			g.send("E01")
+			return false
+		}
+		for i, v := range buf {
+			d.nes.Memory.MemWrite(addr+uint16(i), v)
+		}
+		g.send("OK")
+	case 'Z', 'z':
+		g.handlePoint(p)
+	case 's':
+		g.resume(p, d.StepInto)
+	case 'c':
+		g.resume(p, d.Continue)
+	case 'D':
+		g.send("OK")
+		return true
+	case 'k':
+		return true
+	case 'q':
+		switch {
+		case strings.HasPrefix(p, "qSupported"):
+			g.send(fmt.Sprintf("PacketSize=%x", gdbPacketSize))
+		case p == "qAttached":
+			g.send("1")
+		default:
+			g.send("")
+		}
+	case 'H':
+		// single thread
+		g.send("OK")
+	default:
+		// unsupported
+		g.send("")
+	}
+	return false
+}
+
+// regs returns registers in the format of the "g" packet
+func (g *gdbConn) regs() []byte {
+	cpu := g.d.nes.CPU
+	return []byte{cpu.A, cpu.X, cpu.Y, cpu.S, cpu.P, byte(cpu.PC), byte(cpu.PC >> 8)}
+}
+
+// resume handles "s" and "c", which can have an address to resume at, and
+// waits for the machine to stop
+func (g *gdbConn) resume(p string, f func()) {
+	if len(p) > 1 {
+		addr, err := strconv.ParseUint(p[1:], 16, 16)
+		if err != nil {
+			g.send("E01")
+			return
+		}
+		g.d.nes.CPU.PC = uint16(addr)
+	}
+
+	// drop any interrupt received while stopped
+	select {
+	case <-g.interrupt:
+	default:
+	}
+
+	f()
+
+	for {
+		select {
+		case ev := <-g.d.events:
+			if ev.Reason == ReasonHalt {
+				g.send("S02") // SIGINT
+			} else {
+				g.send("S05") // SIGTRAP
+			}
+			return
+		case <-g.interrupt:
+			g.d.stop()
+			g.send("S02") // SIGINT
+			return
+		case p, ok := <-g.packets:
+			if !ok {
+				// client went away, stop so cleanup can resume the machine
+				g.packets = nil
+				g.closed = true
+				g.d.stop()
+				return
+			}
+			log.Printf("GDB: ignoring packet %q while running", p)
+		}
+	}
+}
+
+// handlePoint handles Z and z packets: type,addr,kind
+func (g *gdbConn) handlePoint(p string) {
+	parts := strings.Split(p[1:], ",")
+	if len(parts) < 3 {
+		g.send("E01")
+		return
+	}
+	addr, ln, err := parseAddrLen(parts[1] + "," + parts[2])
+	if err != nil {
+		g.send("E01")
+		return
+	}
+	key := parts[0] + "," + parts[1] + "," + parts[2]
+
+	if p[0] == 'z' {
+		if id, ok := g.points[key]; ok {
+			g.d.Remove(id)
+			delete(g.points, key)
+		}
+		g.send("OK")
+		return
+	}
+
+	if _, ok := g.points[key]; ok {
+		g.send("OK")
+		return
+	}
+
+	end := addr
+	if ln > 1 {
+		end = addr + uint16(ln-1)
+	}
+
+	switch parts[0] {
+	case "0", "1":
+		// software and hardware breakpoints are the same for us
+		g.points[key] = g.d.AddBreakpoint(int(addr), nil, "").ID
+	case "2":
+		g.points[key] = g.d.AddWatchpoint(addr, end, false, true).ID
+	case "3":
+		g.points[key] = g.d.AddWatchpoint(addr, end, true, false).ID
+	case "4":
+		g.points[key] = g.d.AddWatchpoint(addr, end, true, true).ID
+	default:
+		g.send("")
+		return
+	}
+	g.send("OK")
+}
+
+// parseAddrLen parses "addr,length" in hexadecimal
+func parseAddrLen(s string) (uint16, int, error) {
+	a, l, ok := strings.Cut(s, ",")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid address %q", s)
+	}
+	addr, err := strconv.ParseUint(a, 16, 16)
+	if err != nil {
+		return 0, 0, err
+	}
+	ln, err := strconv.ParseUint(l, 16, 16)
+	if err != nil {
+		return 0, 0, err
+	}
+	return uint16(addr), int(ln), nil
+}
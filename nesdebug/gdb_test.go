@@ -0,0 +1,149 @@
+package nesdebug
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/MagicalTux/gones/pkgnes"
+)
+
+// gdbClient reads what the server sends on c
+func gdbClient(t *testing.T, c net.Conn) *bufio.Reader {
+	t.Helper()
+	c.SetDeadline(time.Now().Add(5 * time.Second))
+	return bufio.NewReader(c)
+}
+
+func expect(t *testing.T, r *bufio.Reader, want string) {
+	t.Helper()
+	buf := make([]byte, len(want))
+	if _, err := io.ReadFull(r, buf); err != nil {
+		t.Fatalf("while waiting for %q: %s", want, err)
+	}
+	if string(buf) != want {
+		t.Fatalf("got %q, want %q", buf, want)
+	}
+}
+
+func TestGDBInterruptWhileStopped(t *testing.T) {
+	nes := pkgnes.New(pkgnes.NTSC)
+	d := New(nes)
+	d.halted = true
+	defer nes.Clk.Stop()
+
+	server, client := net.Pipe()
+	defer client.Close()
+	errc := make(chan error, 1)
+	go func() {
+		errc <- d.serveGDBConn(server)
+		server.Close()
+	}()
+	r := gdbClient(t, client)
+
+	// Ctrl-C while stopped must not report a stop, the next thing received is the answer to "?"
+	client.Write([]byte{0x03})
+	client.Write([]byte("$?#3f"))
+	expect(t, r, "+$S05#b8")
+
+	client.Write([]byte("$D#44"))
+	expect(t, r, "+$OK#9a")
+	if err := <-errc; err != nil {
+		t.Errorf("serveGDBConn: %s", err)
+	}
+}
+
+func TestGDBReadLoopDone(t *testing.T) {
+	server, client := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	g := &gdbConn{
+		c:         server,
+		packets:   make(chan string),
+		interrupt: make(chan struct{}, 1),
+		done:      make(chan struct{}),
+	}
+	finished := make(chan struct{})
+	go func() {
+		g.readLoop()
+		close(finished)
+	}()
+
+	// nobody reads packets anymore once the connection isn't served
+	r := gdbClient(t, client)
+	client.Write([]byte("$?#3f"))
+	expect(t, r, "+")
+	close(g.done)
+
+	select {
+	case <-finished:
+	case <-time.After(5 * time.Second):
+		t.Fatal("readLoop is still blocked after done was closed")
+	}
+}
+
+// packet frames data as a GDB packet
+func packet(data string) string {
+	return fmt.Sprintf("$%s#%02x", data, checksum(data))
+}
+
+func TestGDBConnect(t *testing.T) {
+	tests := []struct {
+		name  string
+		start bool // start the clock before connecting
+	}{
+		{"stopped clock", false},
+		{"faulted CPU", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nes := pkgnes.New(pkgnes.NTSC)
+			nes.Memory.MemWrite(0x200, 0x02) // KIL
+			nes.CPU.PC = 0x200
+			d := New(nes)
+			defer nes.Clk.Stop()
+			if tt.start {
+				if err := nes.Clk.RunCycles(1000); err != nil {
+					t.Fatal(err)
+				}
+				nes.Start()
+			}
+
+			server, client := net.Pipe()
+			defer client.Close()
+			errc := make(chan error, 1)
+			go func() {
+				errc <- d.serveGDBConn(server)
+				server.Close()
+			}()
+			r := gdbClient(t, client)
+
+			// the hook is never reached, connecting must not wait for it
+			client.Write([]byte(packet("?")))
+			expect(t, r, "+"+packet("S05"))
+
+			// memory reads are cut to fit in a packet
+			client.Write([]byte(packet("m0,2000")))
+			expect(t, r, "+$")
+			data, err := r.ReadString('#')
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(data)-1 != gdbPacketSize-4 {
+				t.Errorf("read %d hex digits, want %d", len(data)-1, gdbPacketSize-4)
+			}
+			r.Discard(2)
+
+			client.Write([]byte(packet("D")))
+			expect(t, r, "+"+packet("OK"))
+			if err := <-errc; err != nil {
+				t.Errorf("serveGDBConn: %s", err)
+			}
+		})
+	}
+}
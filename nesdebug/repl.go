@@ -0,0 +1,272 @@
+package nesdebug
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/MagicalTux/gones/memory"
+)
+
+const replHelp = `Commands:
+  b ADDR [if COND]     add breakpoint at ADDR, COND is for example "A==$10 && X<4"
+  b if COND            add breakpoint on a condition anywhere
+  w ADDR[-END] [r|w|rw] add watchpoint (default: w)
+  d ID                 delete breakpoint or watchpoint
+  l                    list breakpoints and watchpoints
+  s                    step into
+  n                    step over
+  f                    step out (finish)
+  c                    continue
+  line N               run to scanline N
+  h                    halt (also: empty line while running)
+  r                    show registers
+  x ADDR [LEN]         dump memory
+  q                    detach debugger and quit
+`
+
+// REPL runs an interactive debugger session reading commands from in and
+// writing to out, until "q" is entered or in is closed. The debugger is
+// detached when REPL returns.
+func (d *Debugger) REPL(in io.Reader, out io.Writer) error {
+	defer d.Detach()
+
+	lines := make(chan string)
+	errs := make(chan error, 1)
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		s := bufio.NewScanner(in)
+		for s.Scan() {
+			select {
+			case lines <- s.Text():
+			case <-done:
+				return
+			}
+		}
+		errs <- s.Err()
+		close(lines)
+	}()
+
+	fmt.Fprintf(out, "gones debugger, type \"help\" for help\n")
+	if d.Halted() {
+		d.printState(out)
+	}
+
+	for {
+		if d.Halted() {
+			fmt.Fprintf(out, "> ")
+		}
+
+		select {
+		case ev := <-d.events:
+			fmt.Fprintf(out, "\n%s\n", ev)
+			d.printState(out)
+		case line, ok := <-lines:
+			if !ok {
+				return <-errs
+			}
+			if quit := d.command(out, strings.TrimSpace(line)); quit {
+				return nil
+			}
+		}
+	}
+}
+
+// command runs a single REPL command, and returns true if the REPL should end
+func (d *Debugger) command(out io.Writer, line string) bool {
+	args := strings.Fields(line)
+	if len(args) == 0 {
+		if !d.Halted() {
+			d.Halt()
+		}
+		return false
+	}
+
+	running := func() bool {
+		if !d.Halted() {
+			fmt.Fprintf(out, "machine is running, halt it first\n")
+			return true
+		}
+		return false
+	}
+
+	switch args[0] {
+	case "help", "?":
+		fmt.Fprint(out, replHelp)
+	case "b", "break":
+		addr := AnyAddress
+		rest := strings.TrimSpace(strings.TrimPrefix(line, args[0]))
+		if len(args) > 1 && args[1] != "if" {
+			a, err := parseAddr(args[1])
+			if err != nil {
+				fmt.Fprintf(out, "%s\n", err)
+				return false
+			}
+			addr = int(a)
+			rest = strings.TrimSpace(strings.TrimPrefix(rest, args[1]))
+		}
+		var desc string
+		if strings.HasPrefix(rest, "if ") {
+			desc = strings.TrimSpace(rest[3:])
+		} else if rest != "" || addr == AnyAddress {
+			fmt.Fprintf(out, "usage: b ADDR [if COND], or b if COND\n")
+			return false
+		}
+		var bp *Breakpoint
+		if desc != "" {
+			cond, err := ParseCondition(desc)
+			if err != nil {
+				fmt.Fprintf(out, "%s\n", err)
+				return false
+			}
+			bp = d.AddBreakpoint(addr, cond, desc)
+		} else {
+			bp = d.AddBreakpoint(addr, nil, "")
+		}
+		fmt.Fprintf(out, "%s\n", bp)
+	case "w", "watch":
+		if len(args) < 2 {
+			fmt.Fprintf(out, "usage: w ADDR[-END] [r|w|rw]\n")
+			return false
+		}
+		if running() {
+			return false
+		}
+		startS, endS, isRange := strings.Cut(args[1], "-")
+		start, err := parseAddr(startS)
+		if err != nil {
+			fmt.Fprintf(out, "%s\n", err)
+			return false
+		}
+		end := start
+		if isRange {
+			if end, err = parseAddr(endS); err != nil {
+				fmt.Fprintf(out, "%s\n", err)
+				return false
+			}
+		}
+		mode := "w"
+		if len(args) > 2 {
+			mode = args[2]
+		}
+		if mode != "r" && mode != "w" && mode != "rw" {
+			fmt.Fprintf(out, "invalid mode %q\n", mode)
+			return false
+		}
+		fmt.Fprintf(out, "%s\n", d.AddWatchpoint(start, end, strings.Contains(mode, "r"), strings.Contains(mode, "w")))
+	case "d", "delete":
+		if len(args) < 2 {
+			fmt.Fprintf(out, "usage: d ID\n")
+			return false
+		}
+		id, err := strconv.Atoi(args[1])
+		if err != nil || !d.Remove(id) {
+			fmt.Fprintf(out, "no such breakpoint or watchpoint: %s\n", args[1])
+		}
+	case "l", "list":
+		bps, ws := d.List()
+		for _, bp := range bps {
+			fmt.Fprintf(out, "%s\n", bp)
+		}
+		for _, w := range ws {
+			fmt.Fprintf(out, "%s\n", w)
+		}
+	case "s", "step":
+		if !running() {
+			d.StepInto()
+		}
+	case "n", "next":
+		if !running() {
+			d.StepOver()
+		}
+	case "f", "finish":
+		if !running() {
+			d.StepOut()
+		}
+	case "c", "continue":
+		if !running() {
+			d.Continue()
+		}
+	case "line":
+		if len(args) < 2 {
+			fmt.Fprintf(out, "usage: line N\n")
+			return false
+		}
+		n, err := strconv.ParseUint(args[1], 10, 16)
+		if err != nil || n >= uint64(d.nes.Model().Scanlines()) {
+			fmt.Fprintf(out, "invalid scanline %s\n", args[1])
+			return false
+		}
+		if !running() {
+			d.RunToScanline(uint16(n))
+		}
+	case "h", "halt":
+		d.Halt()
+	case "r", "regs":
+		if !running() {
+			d.printState(out)
+		}
+	case "x":
+		if len(args) < 2 {
+			fmt.Fprintf(out, "usage: x ADDR [LEN]\n")
+			return false
+		}
+		if running() {
+			return false
+		}
+		addr, err := parseAddr(args[1])
+		if err != nil {
+			fmt.Fprintf(out, "%s\n", err)
+			return false
+		}
+		ln := uint64(0x40)
+		if len(args) > 2 {
+			if ln, err = strconv.ParseUint(args[2], 0, 16); err != nil {
+				fmt.Fprintf(out, "invalid length %s\n", args[2])
+				return false
+			}
+		}
+		d.dump(out, addr, int(ln))
+	case "q", "quit":
+		return true
+	default:
+		fmt.Fprintf(out, "unknown command %q, type \"help\" for help\n", args[0])
+	}
+	return false
+}
+
+func (d *Debugger) printState(out io.Writer) {
+	cpu := d.nes.CPU
+	fmt.Fprintf(out, "%s cyc=%d %s\n", cpu, cpu.Cycles(), d.nes.PPU.Debug())
+	fmt.Fprintf(out, "$%04x: ", cpu.PC)
+	for i := uint16(0); i < 3; i++ {
+		fmt.Fprintf(out, "%02x ", d.Peek(cpu.PC+i))
+	}
+	fmt.Fprintf(out, "\n")
+}
+
+// Peek reads memory as seen by the CPU, without side effects (see
+// memory.Peek)
+func (d *Debugger) Peek(addr uint16) byte {
+	return memory.Peek(d.nes.Memory, addr)
+}
+
+func (d *Debugger) dump(out io.Writer, addr uint16, ln int) {
+	for i := 0; i < ln; i += 16 {
+		a := addr + uint16(i)
+		fmt.Fprintf(out, "$%04x:", a)
+		for j := 0; j < 16 && i+j < ln; j++ {
+			b := a + uint16(j)
+			if b >= 0x2000 && b < 0x4020 {
+				fmt.Fprintf(out, " --")
+			} else {
+				fmt.Fprintf(out, " %02x", d.Peek(b))
+			}
+		}
+		fmt.Fprintf(out, "\n")
+	}
+}
@@ -0,0 +1,283 @@
+package nesinput
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// turbo buttons press A or B repeatedly while held, they are only known to
+// bindings and never read by the NES
+const (
+	buttonTurboA = ButtonRight + 1 + iota
+	buttonTurboB
+	buttonCount
+)
+
+var buttonNames = [buttonCount]string{"A", "B", "Select", "Start", "Up", "Down", "Left", "Right", "TurboA", "TurboB"}
+
+// maxTurboRate is the fastest turbo rate, pressing and releasing a button
+// every other frame
+const maxTurboRate = 30
+
+// framesPerSecond is the NTSC frame rate, used to convert turbo rates to
+// frames
+const framesPerSecond = 60
+
+// FrameCounter counts the frames emulated so far, it times turbo buttons so
+// that they follow the frames polled by the game. It is implemented by
+// nesppu.PPU.
+type FrameCounter interface {
+	Frame() uint64
+}
+
+// padInputs are the names of the standard gamepad buttons, and of the
+// directions of its analog sticks
+// see: https://www.w3.org/TR/gamepad/#remapping
+var padInputs = map[string]padInput{
+	"RightBottom":      {button: ebiten.StandardGamepadButtonRightBottom},
+	"RightRight":       {button: ebiten.StandardGamepadButtonRightRight},
+	"RightLeft":        {button: ebiten.StandardGamepadButtonRightLeft},
+	"RightTop":         {button: ebiten.StandardGamepadButtonRightTop},
+	"FrontTopLeft":     {button: ebiten.StandardGamepadButtonFrontTopLeft},
+	"FrontTopRight":    {button: ebiten.StandardGamepadButtonFrontTopRight},
+	"FrontBottomLeft":  {button: ebiten.StandardGamepadButtonFrontBottomLeft},
+	"FrontBottomRight": {button: ebiten.StandardGamepadButtonFrontBottomRight},
+	"CenterLeft":       {button: ebiten.StandardGamepadButtonCenterLeft},
+	"CenterRight":      {button: ebiten.StandardGamepadButtonCenterRight},
+	"CenterCenter":     {button: ebiten.StandardGamepadButtonCenterCenter},
+	"LeftStick":        {button: ebiten.StandardGamepadButtonLeftStick},
+	"RightStick":       {button: ebiten.StandardGamepadButtonRightStick},
+	"LeftTop":          {button: ebiten.StandardGamepadButtonLeftTop},
+	"LeftBottom":       {button: ebiten.StandardGamepadButtonLeftBottom},
+	"LeftLeft":         {button: ebiten.StandardGamepadButtonLeftLeft},
+	"LeftRight":        {button: ebiten.StandardGamepadButtonLeftRight},
+
+	"LeftStickLeft":   {axis: ebiten.StandardGamepadAxisLeftStickHorizontal, dir: -1},
+	"LeftStickRight":  {axis: ebiten.StandardGamepadAxisLeftStickHorizontal, dir: 1},
+	"LeftStickUp":     {axis: ebiten.StandardGamepadAxisLeftStickVertical, dir: -1},
+	"LeftStickDown":   {axis: ebiten.StandardGamepadAxisLeftStickVertical, dir: 1},
+	"RightStickLeft":  {axis: ebiten.StandardGamepadAxisRightStickHorizontal, dir: -1},
+	"RightStickRight": {axis: ebiten.StandardGamepadAxisRightStickHorizontal, dir: 1},
+	"RightStickUp":    {axis: ebiten.StandardGamepadAxisRightStickVertical, dir: -1},
+	"RightStickDown":  {axis: ebiten.StandardGamepadAxisRightStickVertical, dir: 1},
+}
+
+// padInput is a button of a standard gamepad, or a direction of one of its
+// analog sticks if dir is set
+type padInput struct {
+	button ebiten.StandardGamepadButton
+	axis   ebiten.StandardGamepadAxis
+	dir    float64
+}
+
+// Config is the input configuration, usually loaded from a JSON file with
+// LoadConfig. For example:
+//
+//	{"players": [{
+//		"keyboard": {"A": ["Z"], "B": ["X"], "TurboA": ["A"], "Start": ["Enter"], "Up": ["ArrowUp"]},
+//		"gamepad": {"A": ["RightRight"], "Up": ["LeftTop", "LeftStickUp"]},
+//		"axis_threshold": 0.5,
+//		"turbo_rate": 15
+//	}]}
+type Config struct {
+	Players []*PlayerConfig `json:"players"`
+
+	players []*Player
+}
+
+// PlayerConfig holds the bindings of a player. Keyboard and Gamepad map the
+// buttons of the controller (A, B, Select, Start, Up, Down, Left, Right,
+// TurboA, TurboB) to the names of the keys (as accepted by ebiten.Key's
+// UnmarshalText) or standard gamepad inputs (see padInputs) pressing them.
+type PlayerConfig struct {
+	Keyboard map[string][]string `json:"keyboard"`
+	Gamepad  map[string][]string `json:"gamepad"`
+
+	AxisThreshold float64 `json:"axis_threshold"` // how far sticks must be pushed (0~1), defaults to 0.5
+	TurboRate     float64 `json:"turbo_rate"`     // presses per second of turbo buttons, defaults to 15, at most 30
+}
+
+// Player is the compiled form of a PlayerConfig, used by input devices
+type Player struct {
+	keys      [buttonCount][]ebiten.Key
+	pad       [buttonCount][]padInput
+	threshold float64
+	turbo     uint64 // period of turbo buttons, in frames
+}
+
+// DefaultConfig returns the configuration used when none is given. The
+// keyboard is split between two players, the first one using the arrows and
+// the second one IJKL, and players 3 and 4 (see FourScore) only have gamepads.
+func DefaultConfig() *Config {
+	pad := map[string][]string{
+		"A":      {"RightRight"},
+		"B":      {"RightBottom"},
+		"Select": {"CenterLeft"},
+		"Start":  {"CenterRight"},
+		"Up":     {"LeftTop", "LeftStickUp"},
+		"Down":   {"LeftBottom", "LeftStickDown"},
+		"Left":   {"LeftLeft", "LeftStickLeft"},
+		"Right":  {"LeftRight", "LeftStickRight"},
+		"TurboA": {"RightTop"},
+		"TurboB": {"RightLeft"},
+	}
+
+	c := &Config{
+		Players: []*PlayerConfig{
+			{
+				Keyboard: map[string][]string{
+					"A":      {"Z"},
+					"B":      {"X"},
+					"Select": {"Space"},
+					"Start":  {"Enter"},
+					"Up":     {"ArrowUp"},
+					"Down":   {"ArrowDown"},
+					"Left":   {"ArrowLeft"},
+					"Right":  {"ArrowRight"},
+					"TurboA": {"A"},
+					"TurboB": {"S"},
+				},
+				Gamepad: pad,
+			},
+			{
+				Keyboard: map[string][]string{
+					"A":      {"M"},
+					"B":      {"N"},
+					"Select": {"U"},
+					"Start":  {"O"},
+					"Up":     {"I"},
+					"Down":   {"K"},
+					"Left":   {"J"},
+					"Right":  {"L"},
+				},
+				Gamepad: pad,
+			},
+			{Gamepad: pad},
+			{Gamepad: pad},
+		},
+	}
+	if err := c.compile(); err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// LoadConfig reads the input configuration in JSON format from the given
+// file. It can be called again to reload the file after it changed.
+func LoadConfig(filename string) (*Config, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	c := &Config{}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	if err := c.compile(); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return c, nil
+}
+
+// Player returns the bindings of player n (starting at 0), which have
+// nothing bound if the configuration doesn't have this player
+func (c *Config) Player(n int) *Player {
+	if n < 0 || n >= len(c.players) {
+		return &Player{}
+	}
+	return c.players[n]
+}
+
+func (c *Config) compile() error {
+	c.players = make([]*Player, len(c.Players))
+	for n, pc := range c.Players {
+		p, err := pc.compile()
+		if err != nil {
+			return fmt.Errorf("player %d: %w", n+1, err)
+		}
+		c.players[n] = p
+	}
+	return nil
+}
+
+func (pc *PlayerConfig) compile() (*Player, error) {
+	p := &Player{threshold: pc.AxisThreshold, turbo: framesPerSecond / 15}
+	if p.threshold <= 0 {
+		p.threshold = 0.5
+	}
+	switch {
+	case pc.TurboRate < 0:
+		return nil, fmt.Errorf("invalid turbo_rate %g", pc.TurboRate)
+	case pc.TurboRate > maxTurboRate:
+		// games read the controllers once per frame, faster presses would be missed
+		p.turbo = framesPerSecond / maxTurboRate
+	case pc.TurboRate > 0:
+		p.turbo = uint64(math.Max(math.Round(framesPerSecond/pc.TurboRate), framesPerSecond/maxTurboRate))
+	}
+
+	for name, keys := range pc.Keyboard {
+		btn, err := parseButton(name)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range keys {
+			var k ebiten.Key
+			if err := k.UnmarshalText([]byte(s)); err != nil {
+				return nil, err
+			}
+			p.keys[btn] = append(p.keys[btn], k)
+		}
+	}
+	for name, inputs := range pc.Gamepad {
+		btn, err := parseButton(name)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range inputs {
+			in, ok := padInputs[s]
+			if !ok {
+				return nil, fmt.Errorf("unknown gamepad input %q", s)
+			}
+			p.pad[btn] = append(p.pad[btn], in)
+		}
+	}
+	return p, nil
+}
+
+func parseButton(name string) (byte, error) {
+	for n, s := range buttonNames {
+		if s == name {
+			return byte(n), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown button %q", name)
+}
+
+// pressed returns true if btn is pressed during frame according to down,
+// which tells if a binding is held, applying turbo buttons to A and B
+func (p *Player) pressed(btn byte, frame uint64, down func(btn byte) bool) bool {
+	if down(btn) {
+		return true
+	}
+	switch btn {
+	case ButtonA:
+		return down(buttonTurboA) && p.turboOn(frame)
+	case ButtonB:
+		return down(buttonTurboB) && p.turboOn(frame)
+	default:
+		return false
+	}
+}
+
+// turboOn returns true during the first half of each turbo period
+func (p *Player) turboOn(frame uint64) bool {
+	if p.turbo == 0 {
+		// not compiled from a PlayerConfig
+		return false
+	}
+	return frame%p.turbo < p.turbo/2
+}
@@ -0,0 +1,64 @@
+package nesinput
+
+import "testing"
+
+func TestTurboRate(t *testing.T) {
+	tests := []struct {
+		rate float64
+		want uint64 // period in frames, 0 if the rate is rejected
+	}{
+		{0, 4},
+		{10, 6},
+		{25, 2},
+		{30, 2},
+		{1e300, 2},
+		{0.001, 60000},
+		{-1, 0},
+	}
+
+	for _, tt := range tests {
+		p, err := (&PlayerConfig{TurboRate: tt.rate}).compile()
+		if tt.want == 0 {
+			if err == nil {
+				t.Errorf("turbo_rate %g accepted", tt.rate)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("turbo_rate %g: %s", tt.rate, err)
+			continue
+		}
+		if p.turbo != tt.want {
+			t.Errorf("turbo_rate %g: period %d frames, want %d", tt.rate, p.turbo, tt.want)
+		}
+	}
+
+	// a player without bindings never has turbo
+	if (&Player{}).turboOn(0) {
+		t.Errorf("turbo on without a period")
+	}
+}
+
+func TestTurboFrames(t *testing.T) {
+	p, err := (&PlayerConfig{TurboRate: 10}).compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	down := func(btn byte) bool { return btn == buttonTurboA }
+
+	// pressed for the first 3 frames of each 6, and only on A
+	var got []bool
+	for frame := uint64(0); frame < 12; frame++ {
+		got = append(got, p.pressed(ButtonA, frame, down))
+		if p.pressed(ButtonB, frame, down) {
+			t.Errorf("B pressed by TurboA on frame %d", frame)
+		}
+	}
+	want := []bool{true, true, true, false, false, false, true, true, true, false, false, false}
+	for n := range want {
+		if got[n] != want[n] {
+			t.Errorf("A on frames 0-11 = %v, want %v", got, want)
+			break
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package nesinput
+
+// FourScore is the NES Four Score adapter, connecting 4 controllers to the 2
+// ports of the NES. Each port reads 8 buttons of its first controller, 8
+// buttons of its second one, and 8 bits of signature that games check to
+// detect the adapter.
+// see: https://www.nesdev.org/wiki/Four_player_adapters
+type FourScore struct {
+	ports [2]FourScorePort
+}
+
+// FourScorePort is one of the ports of a FourScore, to be connected to the
+// NES
+type FourScorePort struct {
+	Generic
+}
+
+// fourScoreBits is the report of a FourScore port
+type fourScoreBits struct {
+	devices   [2]ButtonDevice
+	signature byte
+}
+
+// NewFourScore returns a Four Score with the given controllers, controllers 1
+// and 3 being read on port 1 and 2 and 4 on port 2. Controllers can be nil if
+// not connected.
+func NewFourScore(c1, c2, c3, c4 ButtonDevice) *FourScore {
+	f := &FourScore{}
+	f.ports[0].ButtonDevice = &fourScoreBits{devices: [2]ButtonDevice{c1, c3}, signature: 0x10}
+	f.ports[1].ButtonDevice = &fourScoreBits{devices: [2]ButtonDevice{c2, c4}, signature: 0x20}
+	return f
+}
+
+// Port returns port n (0 or 1) of the adapter
+func (f *FourScore) Port(n int) *FourScorePort {
+	return &f.ports[n]
+}
+
+// Pressed returns the buttons of the first controller, read while OUT0 is set
+func (b *fourScoreBits) Pressed(btn byte) bool {
+	return b.bit(btn) == 1
+}
+
+func (b *fourScoreBits) bit(n byte) byte {
+	switch {
+	case n < 8:
+		return b.pressed(b.devices[0], n)
+	case n < 16:
+		return b.pressed(b.devices[1], n-8)
+	case n < 24:
+		// the signature is sent MSB first
+		return b.signature >> (23 - n) & 1
+	default:
+		// the adapter sends 1s once the report is done
+		return 1
+	}
+}
+
+func (b *fourScoreBits) pressed(dev ButtonDevice, btn byte) byte {
+	if dev != nil && dev.Pressed(btn) {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,41 @@
+package nesinput
+
+import "testing"
+
+// buttons is a controller with a fixed set of pressed buttons
+type buttons byte
+
+func (b buttons) Pressed(btn byte) bool {
+	return b>>btn&1 == 1
+}
+
+func TestFourScore(t *testing.T) {
+	f := NewFourScore(buttons(1<<ButtonA|1<<ButtonStart), nil, buttons(1<<ButtonRight), buttons(1<<ButtonB))
+
+	tests := []struct {
+		port int
+		want string // bits read after a strobe
+	}{
+		{0, "10010000" + "00000001" + "00010000" + "1111"},
+		{1, "00000000" + "01000000" + "00100000" + "1111"},
+	}
+
+	for _, tt := range tests {
+		p := f.Port(tt.port)
+
+		// OUT0 set keeps returning button A of the first controller
+		p.Write(1)
+		for i := 0; i < 3; i++ {
+			if v := p.Read(); v != tt.want[0]-'0' {
+				t.Errorf("port %d: read %d while strobing, want %c", tt.port+1, v, tt.want[0])
+			}
+		}
+		p.Write(0)
+
+		for n := range tt.want {
+			if v := p.Read(); v != tt.want[n]-'0' {
+				t.Errorf("port %d: bit %d is %d, want %c", tt.port+1, n, v, tt.want[n])
+			}
+		}
+	}
+}
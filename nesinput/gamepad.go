@@ -7,35 +7,36 @@ import (
 )
 
 type gamepad struct {
-	id ebiten.GamepadID
+	id     ebiten.GamepadID
+	p      *Player
+	frames FrameCounter
 }
 
-func NewGamepad(id ebiten.GamepadID) (*Generic, error) {
+// NewGamepad returns a controller using the gamepad bindings of p on the
+// gamepad id, which must have a standard layout, with turbo buttons timed by
+// frames
+func NewGamepad(id ebiten.GamepadID, p *Player, frames FrameCounter) (*Generic, error) {
 	if !ebiten.IsStandardGamepadLayoutAvailable(id) {
 		return nil, fmt.Errorf("no layout available for gamepad %s", ebiten.GamepadName(id))
 	}
-	return &Generic{ButtonDevice: &gamepad{id}}, nil
+	return &Generic{ButtonDevice: &gamepad{id, p, frames}}, nil
 }
 
 func (c *gamepad) Pressed(btn byte) bool {
-	switch btn {
-	case ButtonA:
-		return ebiten.IsStandardGamepadButtonPressed(c.id, ebiten.StandardGamepadButtonRightRight)
-	case ButtonB:
-		return ebiten.IsStandardGamepadButtonPressed(c.id, ebiten.StandardGamepadButtonRightBottom)
-	case ButtonSelect:
-		return ebiten.IsStandardGamepadButtonPressed(c.id, ebiten.StandardGamepadButtonCenterLeft)
-	case ButtonStart:
-		return ebiten.IsStandardGamepadButtonPressed(c.id, ebiten.StandardGamepadButtonCenterRight)
-	case ButtonUp:
-		return ebiten.IsStandardGamepadButtonPressed(c.id, ebiten.StandardGamepadButtonLeftTop)
-	case ButtonDown:
-		return ebiten.IsStandardGamepadButtonPressed(c.id, ebiten.StandardGamepadButtonLeftBottom)
-	case ButtonLeft:
-		return ebiten.IsStandardGamepadButtonPressed(c.id, ebiten.StandardGamepadButtonLeftLeft)
-	case ButtonRight:
-		return ebiten.IsStandardGamepadButtonPressed(c.id, ebiten.StandardGamepadButtonLeftRight)
-	default:
-		return false
+	return c.p.pressed(btn, c.frames.Frame(), c.down)
+}
+
+// down returns true if a gamepad input bound to btn is held, or an analog
+// stick bound to it is pushed further than the threshold
+func (c *gamepad) down(btn byte) bool {
+	for _, in := range c.p.pad[btn] {
+		if in.dir == 0 {
+			if ebiten.IsStandardGamepadButtonPressed(c.id, in.button) {
+				return true
+			}
+		} else if ebiten.StandardGamepadAxisValue(c.id, in.axis)*in.dir >= c.p.threshold {
+			return true
+		}
 	}
+	return false
 }
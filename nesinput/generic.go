@@ -4,6 +4,12 @@ type ButtonDevice interface {
 	Pressed(key byte) bool
 }
 
+// bitReader is implemented by devices reporting more than the 8 buttons of a
+// standard controller, bit returns bit n of the report sent after a strobe
+type bitReader interface {
+	bit(n byte) byte
+}
+
 type Generic struct {
 	ButtonDevice
 	index byte
@@ -21,10 +27,15 @@ func (c *Generic) Read() byte {
 	}
 
 	var val byte
-	if c.index < 8 && c.Pressed(c.index) {
+	if br, ok := c.ButtonDevice.(bitReader); ok {
+		val = br.bit(c.index)
+	} else if c.index < 8 && c.Pressed(c.index) {
 		val = 1
 	}
-	c.index += 1
+	if c.index < 0xff {
+		// stop counting instead of wrapping back to the first button
+		c.index += 1
+	}
 	return val
 }
 
@@ -2,31 +2,27 @@ package nesinput
 
 import "github.com/hajimehoshi/ebiten/v2"
 
-type keyboard struct{}
+type keyboard struct {
+	p      *Player
+	frames FrameCounter
+}
 
-func NewKeyboard() *Generic {
-	return &Generic{ButtonDevice: &keyboard{}}
+// NewKeyboard returns a controller using the keyboard bindings of p, with
+// turbo buttons timed by frames
+func NewKeyboard(p *Player, frames FrameCounter) *Generic {
+	return &Generic{ButtonDevice: &keyboard{p, frames}}
 }
 
 func (k *keyboard) Pressed(btn byte) bool {
-	switch btn {
-	case ButtonA:
-		return ebiten.IsKeyPressed(ebiten.KeyZ)
-	case ButtonB:
-		return ebiten.IsKeyPressed(ebiten.KeyX)
-	case ButtonSelect:
-		return ebiten.IsKeyPressed(ebiten.KeySpace)
-	case ButtonStart:
-		return ebiten.IsKeyPressed(ebiten.KeyEnter)
-	case ButtonUp:
-		return ebiten.IsKeyPressed(ebiten.KeyArrowUp)
-	case ButtonDown:
-		return ebiten.IsKeyPressed(ebiten.KeyArrowDown)
-	case ButtonLeft:
-		return ebiten.IsKeyPressed(ebiten.KeyArrowLeft)
-	case ButtonRight:
-		return ebiten.IsKeyPressed(ebiten.KeyArrowRight)
-	default:
-		return false
+	return k.p.pressed(btn, k.frames.Frame(), k.down)
+}
+
+// down returns true if a key bound to btn is held
+func (k *keyboard) down(btn byte) bool {
+	for _, key := range k.p.keys[btn] {
+		if ebiten.IsKeyPressed(key) {
+			return true
+		}
 	}
+	return false
 }
@@ -0,0 +1,165 @@
+package nesinput
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// Ports assigns the keyboard and gamepads to the controllers of up to 4
+// players. Each player's controller is pressed by the player's keyboard
+// bindings, and by a gamepad when one is assigned to it: gamepads go to the
+// first player without one in connection order, and when a gamepad is
+// disconnected its player is left with the keyboard until another one is
+// connected, or another one waiting for a free port is assigned to it.
+type Ports struct {
+	mu    sync.Mutex // protects ports from Update while the NES reads them
+	ports []*port
+	spare []ebiten.GamepadID // gamepads waiting for a free port
+}
+
+type port struct {
+	Generic
+	kbd keyboard
+	pad *gamepad // nil if no gamepad is assigned
+}
+
+// NewPorts returns n controllers (1 to 4) using the bindings of cfg, with
+// turbo buttons timed by frames
+func NewPorts(cfg *Config, n int, frames FrameCounter) *Ports {
+	p := &Ports{ports: make([]*port, n)}
+	for i := range p.ports {
+		pt := &port{kbd: keyboard{cfg.Player(i), frames}}
+		pt.ButtonDevice = &portButtons{p, pt}
+		p.ports[i] = pt
+	}
+	return p
+}
+
+// Len returns the number of controllers
+func (p *Ports) Len() int {
+	return len(p.ports)
+}
+
+// Device returns the controller of player n, starting at 0
+func (p *Ports) Device(n int) *Generic {
+	return &p.ports[n].Generic
+}
+
+// SetConfig replaces the bindings of all players, for example after the
+// configuration was reloaded
+func (p *Ports) SetConfig(cfg *Config) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for n, pt := range p.ports {
+		pt.kbd.p = cfg.Player(n)
+		if pt.pad != nil {
+			pt.pad.p = pt.kbd.p
+		}
+	}
+}
+
+// Update assigns newly connected gamepads and releases disconnected ones. It
+// must be called from ebiten's Update, and returns true if the assignments
+// changed.
+func (p *Ports) Update() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	changed := false
+	for n, pt := range p.ports {
+		if pt.pad != nil && inpututil.IsGamepadJustDisconnected(pt.pad.id) {
+			log.Printf("Input: gamepad disconnected from port %d", n+1)
+			pt.pad = nil
+			changed = true
+		}
+	}
+	spare := p.spare[:0]
+	for _, id := range p.spare {
+		if inpututil.IsGamepadJustDisconnected(id) {
+			changed = true
+			continue
+		}
+		spare = append(spare, id)
+	}
+	p.spare = spare
+
+	for _, id := range inpututil.AppendJustConnectedGamepadIDs(nil) {
+		if !ebiten.IsStandardGamepadLayoutAvailable(id) {
+			log.Printf("Input: no layout available for gamepad %s", ebiten.GamepadName(id))
+			continue
+		}
+		p.spare = append(p.spare, id)
+		changed = true
+	}
+
+	// assign waiting gamepads in connection order
+	for len(p.spare) > 0 {
+		pt := p.free()
+		if pt == nil {
+			break
+		}
+		pt.pad = &gamepad{id: p.spare[0], p: pt.kbd.p, frames: pt.kbd.frames}
+		p.spare = p.spare[1:]
+		changed = true
+	}
+
+	if changed {
+		log.Printf("Input: %s", strings.ReplaceAll(p.string(), "\n", ", "))
+	}
+	return changed
+}
+
+// free returns the first port without a gamepad, or nil
+func (p *Ports) free() *port {
+	for _, pt := range p.ports {
+		if pt.pad == nil {
+			return pt
+		}
+	}
+	return nil
+}
+
+// String returns the devices of each player, one line per player
+func (p *Ports) String() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.string()
+}
+
+func (p *Ports) string() string {
+	var r []string
+	for n, pt := range p.ports {
+		dev := "keyboard"
+		if pt.pad != nil {
+			dev = fmt.Sprintf("keyboard + %s", ebiten.GamepadName(pt.pad.id))
+		}
+		r = append(r, fmt.Sprintf("P%d: %s", n+1, dev))
+	}
+	for _, id := range p.spare {
+		r = append(r, fmt.Sprintf("no port: %s", ebiten.GamepadName(id)))
+	}
+	return strings.Join(r, "\n")
+}
+
+// portButtons are the buttons of a port, pressed by the keyboard or the
+// gamepad of the port
+type portButtons struct {
+	ports *Ports
+	pt    *port
+}
+
+func (b *portButtons) Pressed(btn byte) bool {
+	b.ports.mu.Lock()
+	defer b.ports.mu.Unlock()
+
+	if b.pt.kbd.Pressed(btn) {
+		return true
+	}
+	return b.pt.pad != nil && b.pt.pad.Pressed(btn)
+}
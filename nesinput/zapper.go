@@ -0,0 +1,94 @@
+package nesinput
+
+import (
+	"image/color"
+	"sync"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// zapperLightLines is the number of scanlines during which the Zapper senses
+// a bright pixel after the beam passed it
+const zapperLightLines = 20
+
+// visibleScanlines is the number of scanlines output to the screen, the
+// following ones (vblank, and the pre-render scanline) light nothing
+const visibleScanlines = 240
+
+// Screen is the screen a Zapper is aimed at, see nesppu.PPU.Rendered and
+// nesppu.PPU.Scanline
+type Screen interface {
+	Rendered(x, y int) (c color.RGBA, lines int, ok bool)
+	Scanline() uint16
+}
+
+// Zapper is the NES Zapper light gun, usually connected to port 2. It is
+// aimed with the mouse over the window and fired with the left button.
+// see: https://www.nesdev.org/wiki/Zapper
+type Zapper struct {
+	screen Screen
+
+	mu      sync.Mutex
+	x, y    int  // cursor position, in screen pixels
+	trigger bool // left mouse button pressed
+}
+
+// NewZapper returns a Zapper aimed at screen
+func NewZapper(screen Screen) *Zapper {
+	return &Zapper{screen: screen}
+}
+
+// Update samples the mouse, which can only be read from ebiten's Update. It
+// must be called from there.
+func (z *Zapper) Update() {
+	x, y := ebiten.CursorPosition()
+	trigger := ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft)
+
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	z.x, z.y, z.trigger = x, y, trigger
+}
+
+// Read returns the light sense (bit 3, 0 when light is detected) and the
+// trigger (bit 4) of the Zapper, as of the last Update
+func (z *Zapper) Read() byte {
+	z.mu.Lock()
+	x, y, trigger := z.x, z.y, z.trigger
+	z.mu.Unlock()
+
+	var val byte
+	if !z.light(x, y) {
+		val |= 0x08
+	}
+	if trigger {
+		val |= 0x10
+	}
+	return val
+}
+
+// Write does nothing, the Zapper isn't strobed
+func (z *Zapper) Write(value byte) {
+}
+
+// light returns true if the screen around x, y is bright and was rendered
+// recently. The cursor position is given in screen pixels as the window's
+// layout is the size of the NES screen.
+func (z *Zapper) light(x, y int) bool {
+	if z.screen.Scanline() >= visibleScanlines {
+		// the screen is dark during vblank
+		return false
+	}
+	for dy := -1; dy <= 1; dy++ {
+		for dx := -1; dx <= 1; dx++ {
+			c, lines, ok := z.screen.Rendered(x+dx, y+dy)
+			if !ok || lines > zapperLightLines {
+				continue
+			}
+			// only light colors, such as the white targets drawn by games, are seen
+			if (299*int(c.R)+587*int(c.G)+114*int(c.B))/1000 >= 0xc0 {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package nesinput
+
+import (
+	"image/color"
+	"testing"
+)
+
+// testScreen is a screen with a white target at (100, 100)~(110, 110),
+// rendered lines scanlines ago, currently rendering scanline
+type testScreen struct {
+	lines    int
+	scanline uint16
+}
+
+func (s testScreen) Scanline() uint16 {
+	return s.scanline
+}
+
+func (s testScreen) Rendered(x, y int) (color.RGBA, int, bool) {
+	if x >= 100 && x < 110 && y >= 100 && y < 110 {
+		return color.RGBA{0xff, 0xff, 0xff, 0xff}, s.lines, true
+	}
+	return color.RGBA{}, s.lines, true
+}
+
+func TestZapper(t *testing.T) {
+	tests := []struct {
+		name     string
+		x, y     int
+		lines    int
+		scanline uint16
+		trigger  bool
+		want     byte
+	}{
+		{"aimed at the target", 105, 105, 1, 110, false, 0x00},
+		{"next to the target", 110, 105, 1, 110, true, 0x10},
+		{"away from the target", 50, 50, 1, 110, true, 0x18},
+		{"target rendered long ago", 105, 105, zapperLightLines + 1, 131, false, 0x08},
+		{"vblank", 105, 105, 1, 241, false, 0x08},
+		{"pre-render scanline", 105, 105, 1, 261, false, 0x08},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			z := NewZapper(testScreen{tt.lines, tt.scanline})
+			// as sampled by Update
+			z.x, z.y, z.trigger = tt.x, tt.y, tt.trigger
+
+			if v := z.Read(); v != tt.want {
+				t.Errorf("Read() = $%02x, want $%02x", v, tt.want)
+			}
+		})
+	}
+}
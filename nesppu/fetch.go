@@ -2,16 +2,36 @@ package nesppu
 
 // fetch/store pipeline methods
 
+// a12Filter is the number of PPU cycles A12 needs to stay low for a rising
+// edge to be reported. MMC3 needs A12 to be low for 3 falling edges of M2,
+// which filters out the rises happening during background tile fetches.
+const a12Filter = 10
+
+// read reads from the PPU memory during rendering, keeping track of the A12
+// address line for mappers that need it
+func (p *PPU) read(addr uint16) byte {
+	a12 := addr&0x1000 == 0x1000
+	if a12 != p.a12 {
+		p.a12 = a12
+		if !a12 {
+			p.a12Low = p.dots
+		} else if p.dots-p.a12Low >= a12Filter && p.A12Rising != nil {
+			p.A12Rising()
+		}
+	}
+	return p.Memory.MemRead(addr)
+}
+
 func (p *PPU) fetchNameTableByte() {
 	addr := 0x2000 | (p.V & 0x0FFF)
-	p.nameTableByte = p.Memory.MemRead(addr)
+	p.nameTableByte = p.read(addr)
 }
 
 func (p *PPU) fetchAttributeTableByte() {
 	v := p.V
 	addr := 0x23C0 | (v & 0x0C00) | ((v >> 4) & 0x38) | ((v >> 2) & 0x07)
 	shift := ((v >> 4) & 4) | (v & 2)
-	p.attributeTableByte = ((p.Memory.MemRead(addr) >> shift) & 3) << 2
+	p.attributeTableByte = ((p.read(addr) >> shift) & 3) << 2
 }
 
 func (p *PPU) currentTileAddress() uint16 {
@@ -25,11 +45,11 @@ func (p *PPU) currentTileAddress() uint16 {
 }
 
 func (p *PPU) fetchLowTileByte() {
-	p.lowTileByte = p.Memory.MemRead(p.currentTileAddress())
+	p.lowTileByte = p.read(p.currentTileAddress())
 }
 
 func (p *PPU) fetchHighTileByte() {
-	p.highTileByte = p.Memory.MemRead(p.currentTileAddress() + 8)
+	p.highTileByte = p.read(p.currentTileAddress() + 8)
 }
 
 func (p *PPU) storeTileData() {
@@ -3,6 +3,7 @@ package nesppu
 import (
 	"fmt"
 	"image"
+	"image/color"
 	"io"
 	"sync"
 
@@ -43,6 +44,9 @@ type PPU struct {
 
 	readBuf byte // read buffer for PPUDATA
 
+	ioBus     byte      // I/O latch, see openBus
+	ioRefresh [8]uint64 // frame each bit of ioBus was last refreshed
+
 	// variables used during rendering
 	nameTableByte      byte
 	attributeTableByte byte
@@ -50,6 +54,7 @@ type PPU struct {
 	highTileByte       byte
 	tileData           uint64
 	nameTableMemory    memory.RAM
+	mirroring          MirroringOption // last mirroring set, InvalidMirroring if custom
 
 	// sprites
 	spriteCount      int
@@ -58,9 +63,19 @@ type PPU struct {
 	spritePriorities [8]byte
 	spriteIndexes    [8]byte
 
+	// A12 line tracking, for mappers counting scanlines
+	dots   uint64 // number of PPU cycles since power on
+	a12    bool   // current state of PPU A12
+	a12Low uint64 // value of dots when A12 went low
+
 	front, back     *image.RGBA
 	frontLk         sync.Mutex
-	VBlankInterrupt func(byte)
+	VBlankInterrupt func()
+
+	// A12Rising is called when the PPU address line A12 rises after having
+	// been low for a few cycles. Mappers such as MMC3 use it to count
+	// scanlines.
+	A12Rising func()
 
 	// Debug trace
 	Trace io.Writer
@@ -75,6 +90,7 @@ func New() *PPU {
 		back:            image.NewRGBA(image.Rect(0, 0, 256, 240)),
 		sync:            make(chan *image.RGBA),
 		nameTableMemory: memory.NewRAM(0x800), // NEW standard 2kB PPU work ram
+		mirroring:       VerticalMirroring,
 		Palette:         initialPalette,
 	}
 
@@ -117,6 +133,7 @@ func (p *PPU) Clock(cnt uint64) uint64 {
 
 	for xrun := uint64(0); xrun < cnt; xrun += 1 {
 		p.cycle += 1
+		p.dots += 1
 
 		if p.cycle == 341 {
 			// increase scanline
@@ -184,6 +201,42 @@ func (p *PPU) Front(cb func(*image.RGBA)) {
 	cb(p.front)
 }
 
+// Frame returns the number of frames rendered since the last reset
+func (p *PPU) Frame() uint64 {
+	return p.frame
+}
+
+// Scanline returns the scanline currently being rendered (0~261, 261 being
+// the pre-render scanline)
+func (p *PPU) Scanline() uint16 {
+	return p.scanline
+}
+
+// Cycle returns the current cycle (dot) within the scanline (0~340)
+func (p *PPU) Cycle() uint16 {
+	return p.cycle
+}
+
+// Rendered returns the pixel at x, y of the frame being rendered and the
+// number of scanlines since it was output, or ok=false if it wasn't output in
+// this frame yet. Light sensing devices such as the Zapper use it to see the
+// screen as the beam passes, and must call it from the emulation (for
+// example when their port is read) for the position to be accurate.
+func (p *PPU) Rendered(x, y int) (c color.RGBA, lines int, ok bool) {
+	if x < 0 || x >= 256 || y < 0 || y >= 240 || p.scanline >= 240 {
+		return
+	}
+	if !p.getMask(ShowBg) && !p.getMask(ShowSprites) {
+		// nothing is rendered, see Clock
+		return
+	}
+	l := int(p.scanline) - y
+	if l < 0 || l == 0 && x >= int(p.cycle)-1 {
+		return
+	}
+	return p.back.RGBAAt(x, y), l, true
+}
+
 func (p *PPU) checkPendingNMI() {
 	// only actually send NMI after 3 PPU clocks because it's likely when the CPU would detect it
 	// this gives the opportunity for the NMI to not happen if a read on PPUSTATUS happens before the NMI is sent
@@ -193,7 +246,7 @@ func (p *PPU) checkPendingNMI() {
 	// check if we have any pending NMI, and send it
 	if p.vblankNMI && p.getFlag(GenerateNMI) && p.vblankDoNMI {
 		p.vblankNMI = false
-		p.sendInterrupt(1)
+		p.sendInterrupt()
 	}
 }
 
@@ -204,11 +257,11 @@ func (p *PPU) trace(msg string, arg ...any) {
 	fmt.Fprintf(p.Trace, "%s: "+msg+"\n", append([]any{p.Debug()}, arg...)...)
 }
 
-func (p *PPU) sendInterrupt(v byte) {
+func (p *PPU) sendInterrupt() {
 	p.trace("NMI sent")
 	if f := p.VBlankInterrupt; f != nil {
 		// trigger vblank interrupt
-		f(v)
+		f()
 	}
 }
 
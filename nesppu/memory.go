@@ -30,24 +30,31 @@ func (p *PPU) MemRead(offset uint16) byte {
 			p.vblankNMI = false
 			p.vblankDoNMI = false
 		}
-		return stat
+		// only the top 3 bits are driven, the others come from the I/O latch
+		p.setOpenBus(stat, 0xe0)
+		return p.openBus()
 	case OAMDATA:
 		// read OAM data
 		if p.oamAddr&0x03 == 0x02 {
 			// see: https://www.nesdev.org/wiki/PPU_OAM#Byte_2
 			// bits 2, 3, 4 of byte 2 always return zero
-			return p.OAM[p.oamAddr] & 0xe3
+			p.setOpenBus(p.OAM[p.oamAddr]&0xe3, 0xff)
+			return p.openBus()
 		}
-		return p.OAM[p.oamAddr]
+		p.setOpenBus(p.OAM[p.oamAddr], 0xff)
+		return p.openBus()
 	case PPUDATA:
 		// read from memory at address p.ppuAddr
 		// See: https://www.nesdev.org/wiki/PPU_registers#The_PPUDATA_read_buffer_(post-fetch)
 		res := p.readBuf
 		p.readBuf = p.Memory.MemRead(p.V & 0x3fff)
 		if p.V >= 0x3f00 {
-			// return palette data instead
-			res = p.Palette[palAddr(p.V)]
+			// return palette data instead, which only drives the low 6 bits
+			p.setOpenBus(p.Palette[palAddr(p.V)], 0x3f)
+		} else {
+			p.setOpenBus(res, 0xff)
 		}
+		res = p.openBus()
 		p.trace("PPUDATA read: $%04x = $%02x", p.V, res)
 		// increment p.V
 		if !p.getFlag(LargeIncrements) {
@@ -56,14 +63,34 @@ func (p *PPU) MemRead(offset uint16) byte {
 			p.V += 32
 		}
 		return res
-	default:
-		log.Printf("Unhandled PPU read: $%04x", offset)
 	}
-	return 0
+	// write-only registers return the content of the I/O latch
+	return p.openBus()
+}
+
+// Peek returns what MemRead would return, without clearing the vblank flag or
+// moving the PPUDATA address (see memory.Peeker)
+func (p *PPU) Peek(offset uint16) byte {
+	switch offset & 7 {
+	case PPUSTATUS:
+		return p.stat&0xe0 | p.ioBus&0x1f
+	case OAMDATA:
+		if p.oamAddr&0x03 == 0x02 {
+			return p.OAM[p.oamAddr] & 0xe3
+		}
+		return p.OAM[p.oamAddr]
+	case PPUDATA:
+		if p.V >= 0x3f00 {
+			return p.ioBus&0xc0 | p.Palette[palAddr(p.V)]&0x3f
+		}
+		return p.readBuf
+	}
+	return p.ioBus
 }
 
 func (p *PPU) MemWrite(offset uint16, val byte) byte {
 	// only care about first 3 bits (&0x7)
+	p.setOpenBus(val, 0xff)
 
 	switch offset & 7 {
 	case PPUCTRL:
@@ -140,6 +167,32 @@ func (p *PPU) MemWrite(offset uint16, val byte) byte {
 	return 0
 }
 
+// ioDecayFrames is the number of frames after which bits of the I/O latch
+// that weren't refreshed decay to 0 (about 600ms)
+const ioDecayFrames = 36
+
+// openBus returns the content of the PPU I/O latch, which holds the last value
+// written to any register or read from a register that drives the bus.
+// see: https://www.nesdev.org/wiki/Open_bus_behavior#PPU_open_bus
+func (p *PPU) openBus() byte {
+	for n, f := range p.ioRefresh {
+		if p.frame-f > ioDecayFrames {
+			p.ioBus &^= 1 << n
+		}
+	}
+	return p.ioBus
+}
+
+// setOpenBus sets the bits of the I/O latch in mask to v and refreshes them
+func (p *PPU) setOpenBus(v, mask byte) {
+	p.ioBus = p.ioBus&^mask | v&mask
+	for n := range p.ioRefresh {
+		if mask&(1<<n) != 0 {
+			p.ioRefresh[n] = p.frame
+		}
+	}
+}
+
 // palAddr returns the offset within the palette (0~31) for a given memory access address
 func palAddr(v uint16) uint16 {
 	v %= 0x20
@@ -27,6 +27,7 @@ const (
 
 func (ppu *PPU) SetMirroring(mopt MirroringOption) {
 	ppu.Memory.ClearMapping(0x2000, 0x2000)
+	ppu.mirroring = mopt
 
 	switch mopt {
 	case InvalidMirroring:
@@ -73,6 +74,7 @@ func (ppu *PPU) SetMirroring(mopt MirroringOption) {
 // the largest key provided (for example 0,1,2,3 will allocate 4kB of WRAM).
 func (ppu *PPU) SetCustomNametables(device memory.Handler, keys [4]byte) {
 	ppu.Memory.ClearMapping(0x2000, 0x2000)
+	ppu.mirroring = InvalidMirroring
 
 	if device == nil {
 		maxKey := byte(0)
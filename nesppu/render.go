@@ -46,6 +46,9 @@ func (p *PPU) triggerRender() {
 			p.evaluateSprites()
 		} else {
 			p.spriteCount = 0
+			if preLine {
+				p.fetchDummySprites(8)
+			}
 		}
 	}
 }
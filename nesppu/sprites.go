@@ -29,6 +29,21 @@ func (p *PPU) evaluateSprites() {
 		p.stat &= SpriteOverflow
 	}
 	p.spriteCount = count
+	p.fetchDummySprites(8 - count)
+}
+
+// fetchDummySprites performs the pattern fetches the PPU does for unused
+// sprite slots (using tile $FF). Those have no effect on the picture but can
+// be seen by mappers.
+func (p *PPU) fetchDummySprites(n int) {
+	address := p.spriteTableBase() | 0xff0
+	if p.getFlag(WideSprites) {
+		address = 0x1ff0
+	}
+	for i := 0; i < n; i++ {
+		p.read(address)
+		p.read(address + 8)
+	}
 }
 
 func (p *PPU) spriteTableBase() uint16 {
@@ -61,8 +76,8 @@ func (p *PPU) fetchSpritePattern(i, row int) uint32 {
 		address = (uint16(table) << 12) | uint16(tile)<<4 | uint16(row)
 	}
 	a := (attributes & 3) << 2
-	lowTileByte := p.Memory.MemRead(address)
-	highTileByte := p.Memory.MemRead(address + 8)
+	lowTileByte := p.read(address)
+	highTileByte := p.read(address + 8)
 	var data uint32
 	for i := 0; i < 8; i++ {
 		var p1, p2 byte
@@ -0,0 +1,136 @@
+package nesppu
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// ppuState is the serialized form of the PPU, see SaveState
+type ppuState struct {
+	Ctrl, Mask, Stat, Scroll, Data byte
+	OAM                            [256]byte
+	Palette                        [32]byte
+
+	Cycle, Scanline uint16
+	OddFrame        bool
+	Frame           uint64
+
+	VBlankFlag, VBlankNMI, VBlankDoNMI bool
+
+	OAMAddr byte
+	PPUAddr uint16
+
+	V, T uint16
+	X    byte
+	W    bool
+
+	ReadBuf   byte
+	IOBus     byte
+	IORefresh [8]uint64
+
+	NameTableByte, AttributeTableByte byte
+	LowTileByte, HighTileByte         byte
+	TileData                          uint64
+
+	SpriteCount      int32
+	SpritePatterns   [8]uint32
+	SpritePositions  [8]byte
+	SpritePriorities [8]byte
+	SpriteIndexes    [8]byte
+
+	Dots   uint64
+	A12    bool
+	A12Low uint64
+
+	Mirroring     MirroringOption
+	NameTableSize uint16 // followed by the nametable memory
+}
+
+// SaveState writes the state of the PPU, including nametables memory, to w.
+// Memory mapped by the cartridge (CHR, custom nametables) is not included.
+func (p *PPU) SaveState(w io.Writer) error {
+	st := &ppuState{
+		Ctrl:               p.ctrl,
+		Mask:               p.mask,
+		Stat:               p.stat,
+		Scroll:             p.scroll,
+		Data:               p.data,
+		OAM:                p.OAM,
+		Palette:            p.Palette,
+		Cycle:              p.cycle,
+		Scanline:           p.scanline,
+		OddFrame:           p.oddframe,
+		Frame:              p.frame,
+		VBlankFlag:         p.vblankFlag,
+		VBlankNMI:          p.vblankNMI,
+		VBlankDoNMI:        p.vblankDoNMI,
+		OAMAddr:            p.oamAddr,
+		PPUAddr:            p.ppuAddr,
+		V:                  p.V,
+		T:                  p.T,
+		X:                  p.X,
+		W:                  p.W,
+		ReadBuf:            p.readBuf,
+		IOBus:              p.ioBus,
+		IORefresh:          p.ioRefresh,
+		NameTableByte:      p.nameTableByte,
+		AttributeTableByte: p.attributeTableByte,
+		LowTileByte:        p.lowTileByte,
+		HighTileByte:       p.highTileByte,
+		TileData:           p.tileData,
+		SpriteCount:        int32(p.spriteCount),
+		SpritePatterns:     p.spritePatterns,
+		SpritePositions:    p.spritePositions,
+		SpritePriorities:   p.spritePriorities,
+		SpriteIndexes:      p.spriteIndexes,
+		Dots:               p.dots,
+		A12:                p.a12,
+		A12Low:             p.a12Low,
+		Mirroring:          p.mirroring,
+		NameTableSize:      uint16(len(p.nameTableMemory)),
+	}
+	if err := binary.Write(w, binary.LittleEndian, st); err != nil {
+		return err
+	}
+	_, err := w.Write(p.nameTableMemory)
+	return err
+}
+
+// LoadState restores a state written by SaveState
+func (p *PPU) LoadState(r io.Reader) error {
+	var st ppuState
+	if err := binary.Read(r, binary.LittleEndian, &st); err != nil {
+		return err
+	}
+	nt := make([]byte, st.NameTableSize)
+	if _, err := io.ReadFull(r, nt); err != nil {
+		return err
+	}
+
+	p.ctrl, p.mask, p.stat, p.scroll, p.data = st.Ctrl, st.Mask, st.Stat, st.Scroll, st.Data
+	p.OAM = st.OAM
+	p.Palette = st.Palette
+	p.cycle, p.scanline = st.Cycle, st.Scanline
+	p.oddframe, p.frame = st.OddFrame, st.Frame
+	p.vblankFlag, p.vblankNMI, p.vblankDoNMI = st.VBlankFlag, st.VBlankNMI, st.VBlankDoNMI
+	p.oamAddr, p.ppuAddr = st.OAMAddr, st.PPUAddr
+	p.V, p.T, p.X, p.W = st.V, st.T, st.X, st.W
+	p.readBuf = st.ReadBuf
+	p.ioBus, p.ioRefresh = st.IOBus, st.IORefresh
+	p.nameTableByte, p.attributeTableByte = st.NameTableByte, st.AttributeTableByte
+	p.lowTileByte, p.highTileByte = st.LowTileByte, st.HighTileByte
+	p.tileData = st.TileData
+	p.spriteCount = int(st.SpriteCount)
+	p.spritePatterns = st.SpritePatterns
+	p.spritePositions = st.SpritePositions
+	p.spritePriorities = st.SpritePriorities
+	p.spriteIndexes = st.SpriteIndexes
+	p.dots, p.a12, p.a12Low = st.Dots, st.A12, st.A12Low
+
+	// custom nametables (InvalidMirroring) are restored by the cartridge
+	if st.Mirroring != InvalidMirroring {
+		p.SetMirroring(st.Mirroring)
+	}
+	copy(p.nameTableMemory, nt)
+	return nil
+}
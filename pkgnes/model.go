@@ -51,3 +51,16 @@ func (m Model) ppuIntv() uint64 {
 		panic("invalid model")
 	}
 }
+
+// Scanlines returns the number of scanlines of a frame, including the
+// pre-render scanline
+func (m Model) Scanlines() int {
+	switch m {
+	case NTSC:
+		return 262
+	case PAL:
+		return 312
+	default:
+		panic("invalid model")
+	}
+}
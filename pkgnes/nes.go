@@ -16,31 +16,46 @@ type NES struct {
 	APU    *nesapu.APU          // Audio Processing Unit
 	Input  []nesapu.InputDevice // Input devices
 	model  Model                // This NES's Model, NTSC or PAL
+	ram    memory.RAM           // 2kB internal RAM
+
+	// Cartridge is set by the cartridge when it is connected, and is used
+	// by save states
+	Cartridge Cartridge
 }
 
 func New(model Model) *NES {
 	nes := &NES{
+		model:  model,
 		Memory: memory.NewBus(),
 		Clk:    model.newClock(),
-		CPU:    cpu6502.New(),
+		CPU:    cpu6502.New(cpu6502.Variant2A03),
 		PPU:    nesppu.New(),
+		ram:    memory.NewRAM(0x800),
 	}
-	nes.CPU.Memory = nes.Memory              // connect main memory bus to CPU
-	nes.PPU.VBlankInterrupt = nes.CPU.SetNMI // connect PPU's vblank to NMI
+	nes.CPU.Memory = nes.Memory           // connect main memory bus to CPU
+	nes.PPU.VBlankInterrupt = nes.CPU.NMI // connect PPU's vblank to NMI
+	nes.CPU.TracePPU = func() (uint16, uint16) { return nes.PPU.Scanline(), nes.PPU.Cycle() }
 
-	nes.APU = nesapu.New(nes.Memory, nes.CPU.TimeFreeze) // APU has access to the cpu's memory & clock
+	nes.APU = nesapu.New(nes.Memory) // APU has access to the cpu's memory
 	nes.Input = nes.APU.Input[:]
-	nes.APU.Interrupt = nes.CPU.IRQ
+	nes.APU.FrameIRQ = func(v bool) { nes.CPU.SetIRQ(cpu6502.IRQFrameCounter, v) }
+	nes.APU.DMCIRQ = func(v bool) { nes.CPU.SetIRQ(cpu6502.IRQDMC, v) }
+	nes.APU.OAMDMA = nes.CPU.StartOAMDMA // DMA runs on the CPU's bus
+	nes.APU.DMCDMA = nes.CPU.StartDMCDMA
+	nes.CPU.DMCDone = nes.APU.DMCSample
 
 	// setup RAM (2kB=0x800 bytes) with its mirrors
-	nes.Memory.MapHandler(0x0000, 0x2000, memory.NewRAM(0x800))
+	nes.Memory.MapHandler(0x0000, 0x2000, nes.ram)
 	nes.Memory.MapHandler(0x2000, 0x2000, nes.PPU) // PPU at 0x2000
 	nes.Memory.MapHandler(0x4000, 0x2000, nes.APU) // APU at 0x4000
 
 	// setup clock:
 
 	// trigger once every 12 clocks (if NTSC)
-	nes.Clk.Listen(nes.model.cpuIntv(), 0, nes.CPU.Clock)
+	cpu := nes.Clk.Listen(nes.model.cpuIntv(), 0, nes.CPU.Clock)
+
+	// let other parts catch up with the CPU before each of its bus accesses
+	nes.CPU.Sync = func(n uint64) { nes.Clk.CatchUp(cpu, n) }
 
 	// ppu & apu are run with a offset of 1 to ensure they run after the cpu
 	nes.Clk.Listen(nes.model.ppuIntv(), 1, nes.PPU.Clock)
@@ -59,7 +74,38 @@ func (nes *NES) Start() {
 	nes.Clk.Start()
 }
 
+// RunFrame runs the machine synchronously (without tracking real time) until
+// the PPU starts a new frame, at which point the completed frame is available
+// via PPU.Front. It must not be called while the clock is running. If the
+// CPU is stopped by its Hook, clock.ErrStopped is returned.
+func (nes *NES) RunFrame() error {
+	frame := nes.PPU.Frame()
+	return nes.Clk.RunUntil(func() bool { return nes.PPU.Frame() != frame })
+}
+
+// Model returns the model of this NES
+func (nes *NES) Model() Model {
+	return nes.model
+}
+
 func (nes *NES) Reset() {
 	nes.CPU.Reset()
 	nes.PPU.Reset()
 }
+
+// AddWatch installs w on the CPU bus to observe accesses to its range. The
+// emulation is paused while the watch is installed.
+func (nes *NES) AddWatch(w *memory.Watch) {
+	if nes.Clk.Pause() {
+		defer nes.Clk.Start()
+	}
+	nes.Memory.(*memory.Bus).AddWatch(w)
+}
+
+// RemoveWatch removes a watch installed by AddWatch
+func (nes *NES) RemoveWatch(w *memory.Watch) {
+	if nes.Clk.Pause() {
+		defer nes.Clk.Start()
+	}
+	nes.Memory.(*memory.Bus).RemoveWatch(w)
+}
@@ -0,0 +1,151 @@
+package pkgnes
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/MagicalTux/gones/memory"
+)
+
+// StateVersion is the version of the save state format written by SaveState.
+// It must be increased whenever the content of a state changes.
+const StateVersion = 7
+
+var stateMagic = [8]byte{'G', 'o', 'N', 'E', 'S', 'S', 'T', 'A'}
+
+// Cartridge is the part of a cartridge used by save states
+type Cartridge interface {
+	Hash() [32]byte // hash of the ROM data, used to check states are loaded into the right game
+	SaveState(w io.Writer) error
+	LoadState(r io.Reader) error
+}
+
+type stateHeader struct {
+	Magic   [8]byte
+	Version uint16
+	Model   Model
+	ROMHash [32]byte
+}
+
+// SaveState writes the state of the whole machine to w. The emulation is
+// paused while the state is being written.
+func (nes *NES) SaveState(w io.Writer) error {
+	if nes.Clk.Pause() {
+		defer nes.Clk.Start()
+	}
+	return nes.saveState(w)
+}
+
+// LoadState restores the state of the machine from a state written by
+// SaveState. States saved with a different ROM or NES model are rejected. If
+// the state cannot be loaded, the machine is left as it was, unless restoring
+// it fails too in which case the machine should be reset.
+func (nes *NES) LoadState(r io.Reader) error {
+	if nes.Clk.Pause() {
+		defer nes.Clk.Start()
+	}
+
+	var hdr stateHeader
+	if err := binary.Read(r, binary.LittleEndian, &hdr); err != nil {
+		return fmt.Errorf("while reading state header: %w", err)
+	}
+	if hdr.Magic != stateMagic {
+		return errors.New("not a save state")
+	}
+	if hdr.Version != StateVersion {
+		return fmt.Errorf("unsupported save state version %d", hdr.Version)
+	}
+	if hdr.Model != nes.model {
+		return errors.New("save state was made for a different NES model")
+	}
+	if hdr.ROMHash != nes.romHash() {
+		return errors.New("save state was made with a different ROM")
+	}
+
+	// keep the current state so a broken state doesn't leave the machine half loaded
+	var backup bytes.Buffer
+	if err := nes.saveState(&backup); err != nil {
+		return err
+	}
+	backup.Next(binary.Size(&hdr))
+
+	if err := nes.loadState(r); err != nil {
+		if err2 := nes.loadState(&backup); err2 != nil {
+			return fmt.Errorf("%w, and restoring the previous state failed: %s", err, err2)
+		}
+		return err
+	}
+	return nil
+}
+
+func (nes *NES) romHash() [32]byte {
+	if nes.Cartridge == nil {
+		return [32]byte{}
+	}
+	return nes.Cartridge.Hash()
+}
+
+func (nes *NES) saveState(w io.Writer) error {
+	hdr := &stateHeader{
+		Magic:   stateMagic,
+		Version: StateVersion,
+		Model:   nes.model,
+		ROMHash: nes.romHash(),
+	}
+	if err := binary.Write(w, binary.LittleEndian, hdr); err != nil {
+		return err
+	}
+	if err := nes.Clk.SaveState(w); err != nil {
+		return err
+	}
+	if err := nes.CPU.SaveState(w); err != nil {
+		return err
+	}
+	if _, err := w.Write(nes.ram); err != nil {
+		return err
+	}
+	if err := nes.Memory.(*memory.Bus).SaveState(w); err != nil {
+		return err
+	}
+	if err := nes.PPU.SaveState(w); err != nil {
+		return err
+	}
+	if err := nes.APU.SaveState(w); err != nil {
+		return err
+	}
+	if nes.Cartridge != nil {
+		return nes.Cartridge.SaveState(w)
+	}
+	return nil
+}
+
+// loadState loads everything following the header
+func (nes *NES) loadState(r io.Reader) error {
+	if err := nes.Clk.LoadState(r); err != nil {
+		return fmt.Errorf("while loading clock state: %w", err)
+	}
+	if err := nes.CPU.LoadState(r); err != nil {
+		return fmt.Errorf("while loading CPU state: %w", err)
+	}
+	if _, err := io.ReadFull(r, nes.ram); err != nil {
+		return fmt.Errorf("while loading RAM: %w", err)
+	}
+	if err := nes.Memory.(*memory.Bus).LoadState(r); err != nil {
+		return fmt.Errorf("while loading bus state: %w", err)
+	}
+	if err := nes.PPU.LoadState(r); err != nil {
+		return fmt.Errorf("while loading PPU state: %w", err)
+	}
+	if err := nes.APU.LoadState(r); err != nil {
+		return fmt.Errorf("while loading APU state: %w", err)
+	}
+	if nes.Cartridge != nil {
+		if err := nes.Cartridge.LoadState(r); err != nil {
+			return fmt.Errorf("while loading cartridge state: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package pkgnes_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/MagicalTux/gones/memory"
+	"github.com/MagicalTux/gones/nescartridge"
+	"github.com/MagicalTux/gones/pkgnes"
+)
+
+// stateProgram runs at $E000 in the fixed bank of a MMC3 cartridge. It turns
+// on NMI, rendering and the APU, then loops writing a counter to RAM, PRG RAM,
+// the APU and the mapper, so every part of the machine has some state.
+var stateProgram = []byte{
+	0xa9, 0x80, 0x8d, 0x00, 0x20, // LDA #$80, STA $2000
+	0xa9, 0x1e, 0x8d, 0x01, 0x20, // LDA #$1E, STA $2001
+	0xa9, 0x0f, 0x8d, 0x15, 0x40, // LDA #$0F, STA $4015
+	0xe6, 0x00, // $E00F: INC $00
+	0xa5, 0x00, // LDA $00
+	0x8d, 0x00, 0x60, // STA $6000
+	0x8d, 0x02, 0x40, // STA $4002
+	0x8d, 0x00, 0x80, // STA $8000
+	0x8d, 0x01, 0x80, // STA $8001
+	0x4c, 0x0f, 0xe0, // JMP $E00F
+	0xe6, 0x01, 0x40, // $E022: NMI: INC $01, RTI
+	0x40, // $E025: IRQ: RTI
+}
+
+func loadStateROM(t *testing.T) *pkgnes.NES {
+	t.Helper()
+
+	// MMC3 with 32 KB PRG and CHR RAM
+	img := append([]byte("NES\x1a\x02\x00\x40"), make([]byte, 9+0x8000)...)
+	prg := img[16:]
+	copy(prg[0x6000:], stateProgram)
+	copy(prg[0x7ffa:], []byte{0x22, 0xe0, 0x00, 0xe0, 0x25, 0xe0})
+
+	fn := filepath.Join(t.TempDir(), "state.nes")
+	if err := os.WriteFile(fn, img, 0644); err != nil {
+		t.Fatal(err)
+	}
+	data, err := nescartridge.Load(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { data.Close() })
+
+	nes := pkgnes.New(data.Model())
+	nes.APU.NoAudio = true
+	if err := data.Setup(nes); err != nil {
+		t.Fatal(err)
+	}
+	nes.Reset()
+	return nes
+}
+
+func TestStateRoundTrip(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	a := loadStateROM(t)
+	for i := 0; i < 10; i++ {
+		if err := a.RunFrame(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var st bytes.Buffer
+	if err := a.SaveState(&st); err != nil {
+		t.Fatal(err)
+	}
+
+	b := loadStateROM(t)
+	b.Memory.MemWrite(0x0000, ^memory.OpenBus(a.Memory))
+	if err := b.LoadState(bytes.NewReader(st.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	if ob := memory.OpenBus(b.Memory); ob != memory.OpenBus(a.Memory) {
+		t.Errorf("open bus is $%02x after loading, want $%02x", ob, memory.OpenBus(a.Memory))
+	}
+
+	// both machines must be in the same state right after loading, and after running
+	for n := 0; n < 2; n++ {
+		var sa, sb bytes.Buffer
+		if err := a.SaveState(&sa); err != nil {
+			t.Fatal(err)
+		}
+		if err := b.SaveState(&sb); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(sa.Bytes(), sb.Bytes()) {
+			t.Fatalf("states differ after running %d frames", n*5)
+		}
+		for i := 0; i < 5; i++ {
+			if err := a.RunFrame(); err != nil {
+				t.Fatal(err)
+			}
+			if err := b.RunFrame(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if a.CPU.PC == 0 || a.PPU.Frame() < 15 {
+		t.Errorf("program did not run (PC=$%04x, frame %d)", a.CPU.PC, a.PPU.Frame())
+	}
+}
+
+// brokenCart is a cartridge whose state can never be loaded
+type brokenCart struct{}
+
+func (brokenCart) Hash() [32]byte              { return [32]byte{1} }
+func (brokenCart) SaveState(w io.Writer) error { return nil }
+func (brokenCart) LoadState(r io.Reader) error {
+	return errors.New("broken")
+}
+
+func TestLoadStateRestoreFailure(t *testing.T) {
+	nes := pkgnes.New(pkgnes.NTSC)
+	nes.Cartridge = brokenCart{}
+
+	var st bytes.Buffer
+	if err := nes.SaveState(&st); err != nil {
+		t.Fatal(err)
+	}
+	err := nes.LoadState(&st)
+	if err == nil || !strings.Contains(err.Error(), "restoring the previous state failed") {
+		t.Errorf("got error %v, want a failure to restore the previous state", err)
+	}
+}
@@ -0,0 +1,108 @@
+package romtest
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/MagicalTux/gones/memory"
+)
+
+// Status values written at $6000 by blargg's test ROMs, any value below
+// StatusRunning is the final result code.
+// See: https://github.com/christopherpow/nes-test-roms/blob/master/readme.txt
+const (
+	StatusPassed     = 0x00
+	StatusRunning    = 0x80
+	StatusNeedsReset = 0x81
+)
+
+const (
+	blarggStatusAddr = 0x6000
+	blarggTextAddr   = 0x6004
+)
+
+// the signature at $6001-$6003 tells us the data at $6000 is valid
+var blarggSignature = [...]byte{0xde, 0xb0, 0x61}
+
+// Result is the outcome of a test ROM
+type Result struct {
+	Status byte   // final result code, 0 means passed
+	Text   string // text output of the test
+	Frames int    // number of frames it took to run the test
+}
+
+func (r *Result) Passed() bool {
+	return r.Status == StatusPassed
+}
+
+func (r *Result) String() string {
+	if r.Passed() {
+		return "passed"
+	}
+	return fmt.Sprintf("failed (code %d)", r.Status)
+}
+
+// peek reads addr without side effects, so that checking the status doesn't
+// change the open bus or trigger the mapper and watches
+func (m *Machine) peek(addr uint16) byte {
+	return memory.Peek(m.Memory, addr)
+}
+
+// hasSignature returns true if the test ROM has started reporting its status
+func (m *Machine) hasSignature() bool {
+	for i, v := range blarggSignature {
+		if m.peek(blarggStatusAddr+1+uint16(i)) != v {
+			return false
+		}
+	}
+	return true
+}
+
+// text returns the zero terminated text written at $6004
+func (m *Machine) text() string {
+	var buf []byte
+	for addr := uint16(blarggTextAddr); addr < 0x8000; addr++ {
+		v := m.peek(addr)
+		if v == 0 {
+			break
+		}
+		buf = append(buf, v)
+	}
+	return string(buf)
+}
+
+// RunBlargg runs a test ROM that reports its status at $6000 until it
+// completes, or maxFrames frames have been run. An error is returned if the
+// test did not complete in time, with the text output so far if any.
+func (m *Machine) RunBlargg(maxFrames int) (*Result, error) {
+	resetAt := -1
+
+	for frame := 0; frame < maxFrames; frame++ {
+		if err := m.RunFrame(); err != nil {
+			return nil, err
+		}
+
+		if !m.hasSignature() {
+			continue
+		}
+
+		switch st := m.peek(blarggStatusAddr); {
+		case st == StatusRunning:
+		case st == StatusNeedsReset:
+			// the test wants the reset button pressed, wait a bit (at least 100ms) before doing so
+			if resetAt == -1 {
+				resetAt = frame + 10
+			} else if frame >= resetAt {
+				m.Reset()
+				resetAt = -1
+			}
+		case st < StatusRunning:
+			return &Result{Status: st, Text: m.text(), Frames: frame + 1}, nil
+		}
+	}
+
+	if !m.hasSignature() {
+		return nil, errors.New("test did not report any status")
+	}
+	return nil, fmt.Errorf("test did not complete after %d frames (status $%02x): %q", maxFrames, m.peek(blarggStatusAddr), m.text())
+}
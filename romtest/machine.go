@@ -0,0 +1,64 @@
+// Package romtest runs test ROMs headlessly, and understands the result
+// protocols used by common NES test suites such as blargg's tests and nestest.
+package romtest
+
+import (
+	"fmt"
+
+	"github.com/MagicalTux/gones/nescartridge"
+	"github.com/MagicalTux/gones/pkgnes"
+)
+
+// Machine is a NES with a test ROM loaded, run synchronously
+type Machine struct {
+	*pkgnes.NES
+	data *nescartridge.Data
+}
+
+// Load returns a new Machine running the given ROM file, after reset
+func Load(fn string) (*Machine, error) {
+	data, err := nescartridge.Load(fn)
+	if err != nil {
+		return nil, err
+	}
+
+	nes := pkgnes.New(data.Model())
+	nes.APU.NoAudio = true
+
+	if err := data.Setup(nes); err != nil {
+		data.Close()
+		return nil, err
+	}
+	nes.Reset()
+
+	return &Machine{NES: nes, data: data}, nil
+}
+
+// Close releases the cartridge
+func (m *Machine) Close() error {
+	return m.data.Close()
+}
+
+// RunFrames runs n frames
+func (m *Machine) RunFrames(n int) error {
+	for i := 0; i < n; i++ {
+		if err := m.RunFrame(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RunUntil runs the machine until done returns true, checking it after each
+// clock listener call. An error is returned if done didn't return true within
+// maxFrames frames.
+func (m *Machine) RunUntil(done func() bool, maxFrames int) error {
+	end := m.PPU.Frame() + uint64(maxFrames)
+	if err := m.Clk.RunUntil(func() bool { return done() || m.PPU.Frame() >= end }); err != nil {
+		return err
+	}
+	if !done() {
+		return fmt.Errorf("not done after %d frames", maxFrames)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package romtest
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// romDir returns the directory containing the test ROMs, from
+// https://github.com/christopherpow/nes-test-roms (see Makefile)
+func romDir() string {
+	if dir := os.Getenv("NES_TEST_ROMS"); dir != "" {
+		return dir
+	}
+	return filepath.Join("..", "nes-test-roms")
+}
+
+// blargg's test ROMs reporting their status at $6000, with how many frames
+// they may run before being considered stuck
+var blarggROMs = []struct {
+	name   string
+	frames int
+}{
+	{"instr_test-v5/official_only.nes", 60 * 60},
+	{"instr_misc/instr_misc.nes", 30 * 60},
+	{"instr_timing/instr_timing.nes", 60 * 60},
+	{"cpu_interrupts_v2/cpu_interrupts.nes", 30 * 60},
+	{"cpu_dummy_writes/cpu_dummy_writes_oam.nes", 30 * 60},
+	{"cpu_dummy_writes/cpu_dummy_writes_ppumem.nes", 30 * 60},
+	{"cpu_exec_space/test_cpu_exec_space_ppuio.nes", 30 * 60},
+	{"cpu_exec_space/test_cpu_exec_space_apu.nes", 30 * 60},
+	{"apu_test/apu_test.nes", 30 * 60},
+	{"ppu_vbl_nmi/ppu_vbl_nmi.nes", 60 * 60},
+	{"ppu_open_bus/ppu_open_bus.nes", 30 * 60},
+	{"oam_read/oam_read.nes", 30 * 60},
+	{"mmc3_test_2/rom_singles/1-clocking.nes", 30 * 60},
+	{"mmc3_test_2/rom_singles/2-details.nes", 30 * 60},
+	{"mmc3_test_2/rom_singles/3-A12_clocking.nes", 30 * 60},
+	{"mmc3_test_2/rom_singles/4-scanline_timing.nes", 30 * 60},
+}
+
+func TestMain(m *testing.M) {
+	// the emulator logs a lot of things, such as each APU status read
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+func TestBlargg(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test ROMs in short mode")
+	}
+
+	var table []string
+	defer func() {
+		if len(table) > 0 {
+			t.Logf("Results:\n%s", strings.Join(table, "\n"))
+		}
+	}()
+
+	for _, tt := range blarggROMs {
+		rom := tt.name
+		fn := filepath.Join(romDir(), rom)
+		if _, err := os.Stat(fn); err != nil {
+			t.Logf("skipping %s: %s", rom, err)
+			continue
+		}
+
+		var res string
+		t.Run(rom, func(t *testing.T) {
+			m, err := Load(fn)
+			if err != nil {
+				res = "ERROR"
+				t.Fatal(err)
+			}
+			defer m.Close()
+
+			r, err := m.RunBlargg(tt.frames)
+			if err != nil {
+				res = "ERROR"
+				t.Fatal(err)
+			}
+			res = r.String()
+			if !r.Passed() {
+				t.Errorf("%s: %s", r, r.Text)
+			}
+		})
+		table = append(table, fmt.Sprintf("%-48s %s", rom, res))
+	}
+}
+
+func TestNestest(t *testing.T) {
+	fn := filepath.Join(romDir(), "other", "nestest.nes")
+	if _, err := os.Stat(fn); err != nil {
+		t.Skipf("skipping nestest: %s", err)
+	}
+
+	ref, err := os.Open(filepath.Join("..", "doc", "trace_nestest.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ref.Close()
+
+	m, err := Load(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	// automated mode starts at $C000 instead of the reset vector
+	m.CPU.PC = 0xc000
+
+	c := NewTraceChecker(ref)
+	m.CPU.Trace = c
+	// nestest runs in less than a second, and writes its result codes at $02 and $03
+	if err := m.RunUntil(c.Done, 60); err != nil {
+		t.Fatalf("nestest: %s after %d lines, result codes $%02x $%02x", err, c.Line, m.Memory.MemRead(2), m.Memory.MemRead(3))
+	}
+
+	if c.Err != nil {
+		t.Errorf("nestest: %d lines matched, then %s", c.Line, c.Err)
+	} else {
+		t.Logf("nestest: %d lines matched", c.Line)
+	}
+}
@@ -0,0 +1,123 @@
+package romtest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/MagicalTux/gones/memory"
+)
+
+// blarggPrologue runs at $C000: it writes the signature at $6001 and copies
+// the zero terminated text at $D000 to $6004, then continues at $C01C
+var blarggPrologue = []byte{
+	0xa9, 0xde, 0x8d, 0x01, 0x60, // LDA #$DE, STA $6001
+	0xa9, 0xb0, 0x8d, 0x02, 0x60, // LDA #$B0, STA $6002
+	0xa9, 0x61, 0x8d, 0x03, 0x60, // LDA #$61, STA $6003
+	0xa2, 0x00, // LDX #0
+	0xbd, 0x00, 0xd0, // $C011: LDA $D000,X
+	0x9d, 0x04, 0x60, // STA $6004,X
+	0xf0, 0x03, // BEQ $C01C
+	0xe8,       // INX
+	0xd0, 0xf5, // BNE $C011
+}
+
+// blarggResult sets the status to running, waits about 10 frames, then
+// writes code as the final status
+func blarggResult(code byte) []byte {
+	return []byte{
+		0xa9, 0x80, 0x8d, 0x00, 0x60, // $C01C: LDA #$80, STA $6000
+		0xa2, 0x00, 0xa0, 0x00, // LDX #0, LDY #0
+		0x88, 0xd0, 0xfd, // $C025: DEY, BNE $C025
+		0xca, 0xd0, 0xfa, // DEX, BNE $C025
+		0xa9, code, 0x8d, 0x00, 0x60, // LDA #code, STA $6000
+		0x4c, 0x30, 0xc0, // $C030: JMP $C030
+	}
+}
+
+// blarggReset asks for a reset, and passes after it
+var blarggReset = []byte{
+	0xad, 0x00, 0x61, // $C01C: LDA $6100
+	0xc9, 0xa5, // CMP #$A5
+	0xf0, 0x0d, // BEQ $C030
+	0xa9, 0xa5, 0x8d, 0x00, 0x61, // LDA #$A5, STA $6100
+	0xa9, 0x81, 0x8d, 0x00, 0x60, // LDA #$81, STA $6000
+	0x4c, 0x2d, 0xc0, // $C02D: JMP $C02D
+	0xa9, 0x00, 0x8d, 0x00, 0x60, // $C030: LDA #0, STA $6000
+	0x4c, 0x35, 0xc0, // $C035: JMP $C035
+}
+
+// blarggHang stays running forever
+var blarggHang = []byte{
+	0xa9, 0x80, 0x8d, 0x00, 0x60, // $C01C: LDA #$80, STA $6000
+	0x4c, 0x21, 0xc0, // $C021: JMP $C021
+}
+
+// writeBlarggROM writes a NROM cartridge running the prologue then prog, and
+// returns its file name
+func writeBlarggROM(t *testing.T, prog []byte, text string) string {
+	t.Helper()
+
+	img := append([]byte("NES\x1a\x01\x01"), make([]byte, 10+0x4000+0x2000)...)
+	prg := img[16 : 16+0x4000]
+	copy(prg, blarggPrologue)
+	copy(prg[len(blarggPrologue):], prog)
+	copy(prg[0x1000:], text)
+	prg[0x100] = 0x40 // $C100: RTI
+	copy(prg[0x3ffa:], []byte{0x00, 0xc1, 0x00, 0xc0, 0x00, 0xc1})
+
+	fn := filepath.Join(t.TempDir(), "test.nes")
+	if err := os.WriteFile(fn, img, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return fn
+}
+
+func TestBlarggProtocol(t *testing.T) {
+	tests := []struct {
+		name   string
+		prog   []byte
+		status byte
+		err    string
+	}{
+		{"passed", blarggResult(0), StatusPassed, ""},
+		{"failed", blarggResult(3), 3, ""},
+		{"reset", blarggReset, StatusPassed, ""},
+		{"stuck", blarggHang, 0, "test did not complete after 60 frames (status $80)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := Load(writeBlarggROM(t, tt.prog, "synthetic\n"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer m.Close()
+			// the programs never read the status and signature, the harness
+			// must not either (the text gets dummy reads from STA $6004,X)
+			w := &memory.Watch{Start: 0x6000, End: 0x6003, Read: true}
+			m.AddWatch(w)
+
+			r, err := m.RunBlargg(60)
+			if reads, _ := w.Count(); reads != 0 {
+				t.Errorf("the harness read the status %d times through the bus", reads)
+			}
+			if tt.err != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), tt.err) {
+					t.Fatalf("got error %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if r.Status != tt.status || r.Text != "synthetic\n" {
+				t.Errorf("got status %d and text %q, want %d", r.Status, r.Text, tt.status)
+			}
+			if tt.status == StatusPassed && r.Frames < 10 {
+				t.Errorf("passed after %d frames, want the test to run for a while", r.Frames)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package romtest
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/MagicalTux/gones/cpu6502"
+)
+
+// TraceChecker compares a CPU trace to a reference trace line by line. Set
+// it as the CPU's Trace, and run the machine until Done returns true.
+type TraceChecker struct {
+	ref  *bufio.Scanner
+	buf  []byte
+	Line int   // number of lines that matched
+	Err  error // first mismatch, if any
+	done bool
+}
+
+// NewTraceChecker returns a TraceChecker comparing to the reference trace
+// read from ref
+func NewTraceChecker(ref io.Reader) *TraceChecker {
+	return &TraceChecker{ref: bufio.NewScanner(ref)}
+}
+
+// Done returns true once the whole reference trace has been matched, or a
+// mismatch was found (see Err)
+func (c *TraceChecker) Done() bool {
+	return c.done
+}
+
+func (c *TraceChecker) Write(b []byte) (int, error) {
+	c.buf = append(c.buf, b...)
+	for !c.done {
+		pos := bytes.IndexByte(c.buf, '\n')
+		if pos == -1 {
+			break
+		}
+		c.check(string(c.buf[:pos]))
+		c.buf = c.buf[pos+1:]
+	}
+	return len(b), nil
+}
+
+func (c *TraceChecker) check(line string) {
+	if !c.ref.Scan() {
+		// end of reference
+		c.done = true
+		c.Err = c.ref.Err()
+		return
+	}
+	expect := c.ref.Text()
+
+	exp, err := cpu6502.ParseTraceLine(expect)
+	if err == nil {
+		var got cpu6502.TraceLine
+		got, err = cpu6502.ParseTraceLine(line)
+		if diff := exp.Diff(got); err == nil && len(diff) > 0 {
+			err = fmt.Errorf("%s differ, expected:\n%s\ngot:\n%s", strings.Join(diff, " "), expect, line)
+		}
+	}
+	if err != nil {
+		c.done = true
+		c.Err = fmt.Errorf("trace line %d: %w", c.Line+1, err)
+		return
+	}
+	c.Line += 1
+}